@@ -0,0 +1,71 @@
+package gerbst
+
+// nodeArena hands out nodes from pre-allocated slices rather than allocating each individually
+type nodeArena struct {
+	treeNodes []treeNode
+	nodes     []Node
+}
+
+func newNodeArena(capacity int) *nodeArena {
+	a := new(nodeArena)
+	a.treeNodes = make([]treeNode, capacity)
+	a.nodes = make([]Node, capacity)
+	return a
+}
+
+// newTreeNode behaves as the package-level newTreeNode, taking memory from the arena until it is exhausted
+func (a *nodeArena) newTreeNode(key uint, value interface{}, depth uint, side NodeSide, parent *treeNode) *treeNode {
+	if len(a.treeNodes) == 0 {
+		return newTreeNode(key, value, depth, side, parent, nil, nil)
+	}
+
+	node := &a.nodes[0]
+	node.id = nextNodeID()
+	node.key = key
+	node.value = value
+	node.depth = depth
+	node.side = side
+
+	tn := &a.treeNodes[0]
+	tn.init(node, parent, nil, nil)
+
+	a.treeNodes = a.treeNodes[1:]
+	a.nodes = a.nodes[1:]
+	return tn
+}
+
+// NewLockingTreeWithCapacity constructs a new, empty tree that reserves memory for capacity nodes up front, so that the
+// first capacity insertions take their nodes from a single block rather than allocating each individually.  Further
+// insertions allocate as normal.
+//
+// The reserved block lives for as long as any node carved from it is reachable: deleting nodes does not return their
+// memory, and the whole block is only released once every one of its nodes has been deleted and no *Node handed out
+// from it is still held.  Only use this when the tree is expected to grow to roughly capacity and stay there.
+func NewLockingTreeWithCapacity(capacity int, opts ...LockingTreeOption) *LockingTree {
+	lt := NewLockingTree(opts...)
+	if capacity > 0 {
+		lt.arena = newNodeArena(capacity)
+	}
+	return lt
+}
+
+// putArena expects the caller to hold the write lock, and returns true if a new node was created
+func (n *LockingTree) putArena(key uint, value interface{}) bool {
+	if n.root == nil {
+		n.root = n.arena.newTreeNode(key, value, n.depthBase.rootDepth(), NodeSideRoot, nil)
+		return true
+	}
+	parent, exists := n.root.attachPoint(key)
+	if exists {
+		parent.setValue(value)
+		return false
+	}
+	if key < parent.key {
+		parent.left = n.arena.newTreeNode(key, value, parent.depth+1, NodeSideLeft, parent)
+		updateMeta(parent.left)
+	} else {
+		parent.right = n.arena.newTreeNode(key, value, parent.depth+1, NodeSideRight, parent)
+		updateMeta(parent.right)
+	}
+	return true
+}
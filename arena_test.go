@@ -0,0 +1,54 @@
+package gerbst_test
+
+import (
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+	"github.com/dcarbone/gerbst/testutil"
+)
+
+func TestNewLockingTreeWithCapacity(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+
+	// a capacity below the key count exercises the fallback once the arena is exhausted
+	for _, capacity := range []int{0, 3, 6, 100} {
+		lt := gerbst.NewLockingTreeWithCapacity(capacity)
+		for _, k := range keys {
+			lt.Put(k, k)
+		}
+		lt.Put(12, uint(12))
+
+		t.Run("counts", testutil.BuildTestCounts(lt, false, 6, 3, 2))
+		t.Run("depths", testutil.BuildTestDepths(lt, false, 4, 4, 3))
+		t.Run("gets", testutil.BuildTestGets(lt, false, testutil.GetTestsFromKeys(keys, []uint{0, 83, 100})))
+		if err := lt.SelfCheck(); err != nil {
+			t.Logf("Expected tree with capacity %d to pass self check, saw %v", capacity, err)
+			t.Fail()
+		}
+	}
+}
+
+func benchmarkInserts(b *testing.B, reserve bool) {
+	keys := benchmarkKeys(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var lt *gerbst.LockingTree
+		if reserve {
+			lt = gerbst.NewLockingTreeWithCapacity(len(keys))
+		} else {
+			lt = gerbst.NewLockingTree()
+		}
+		for _, k := range keys {
+			lt.Put(k, nil)
+		}
+	}
+}
+
+func BenchmarkLockingTree_Put_NoCapacity(b *testing.B) {
+	benchmarkInserts(b, false)
+}
+
+func BenchmarkLockingTree_Put_WithCapacity(b *testing.B) {
+	benchmarkInserts(b, true)
+}
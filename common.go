@@ -38,3 +38,63 @@ func (ns NodeSide) IsLeft() bool {
 func (ns NodeSide) IsRight() bool {
 	return ns == NodeSideRight
 }
+
+// EvictionPolicy determines which node is removed to make room when a size-capped tree is full
+type EvictionPolicy uint
+
+const (
+	// EvictSmallestKey removes the node with the lowest key
+	EvictSmallestKey EvictionPolicy = iota
+	// EvictDeepest removes the deepest node, preferring the one with the lowest key when several share that depth
+	EvictDeepest
+)
+
+// String returns a printable representation of this eviction policy
+func (ep EvictionPolicy) String() string {
+	switch ep {
+	case EvictSmallestKey:
+		return "SMALLEST_KEY"
+	case EvictDeepest:
+		return "DEEPEST"
+
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// DepthBase determines the depth assigned to the root node of a tree, and so the depth reported for every node
+type DepthBase uint
+
+const (
+	// DepthBaseOne places the root at depth 1.  This is the default for all trees in this package.
+	DepthBaseOne DepthBase = iota
+	// DepthBaseZero places the root at depth 0
+	DepthBaseZero
+)
+
+// String returns a printable representation of this depth base
+func (db DepthBase) String() string {
+	switch db {
+	case DepthBaseOne:
+		return "ONE"
+	case DepthBaseZero:
+		return "ZERO"
+
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// rootDepth returns the depth at which the root node is placed
+func (db DepthBase) rootDepth() uint {
+	if db == DepthBaseZero {
+		return 0
+	}
+	return 1
+}
+
+// Entry is a single key and its value, as returned by Entries and Drain
+type Entry struct {
+	Key   uint
+	Value interface{}
+}
@@ -0,0 +1,108 @@
+package gerbst
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// NodeSearchFunc is used in conjunction with ConcurrentTree.SearchFunc to visit nodes present in the tree, halting
+// when "false" is returned for "continue_"
+type NodeSearchFunc = func(node *Node) (continue_ bool)
+
+// ConcurrentTree is a LockingTree whose SearchFunc is executed by a fixed pool of worker goroutines that is shared
+// across all calls, bounding search concurrency for the lifetime of the tree rather than per call.
+type ConcurrentTree struct {
+	*LockingTree
+
+	workers   int
+	jobs      chan func()
+	closeOnce sync.Once
+}
+
+// NewConcurrentTree constructs a new, empty tree configured with the provided options and starts its worker pool.  A
+// workers value less than 1 is treated as 1.  Close must be called once the tree is no longer needed to stop the pool.
+func NewConcurrentTree(workers int, opts ...LockingTreeOption) *ConcurrentTree {
+	if workers < 1 {
+		workers = 1
+	}
+	ct := new(ConcurrentTree)
+	ct.LockingTree = NewLockingTree(opts...)
+	ct.workers = workers
+	ct.jobs = make(chan func())
+	for i := 0; i < workers; i++ {
+		go ct.work()
+	}
+	return ct
+}
+
+// Workers returns the number of goroutines in this tree's pool
+func (ct *ConcurrentTree) Workers() int {
+	return ct.workers
+}
+
+// Close stops the worker pool.  SearchFunc must not be called after Close.
+func (ct *ConcurrentTree) Close() {
+	ct.closeOnce.Do(func() {
+		close(ct.jobs)
+	})
+}
+
+// SearchFunc executes fn against every node in the tree using the worker pool, halting as soon as practical once any
+// call returns false.  The tree is split into roughly one subtree per worker, with the nodes above the split visited
+// by the calling goroutine.  The order nodes are visited in is not defined, and fn must be safe for concurrent use.
+//
+// The read lock is held for the duration of the search.  fn must not call SearchFunc on the same tree, as the nested
+// call may wait forever on a busy pool.
+func (ct *ConcurrentTree) SearchFunc(fn NodeSearchFunc) {
+	ct.rlock()
+	defer ct.mu.RUnlock()
+
+	if ct.root == nil {
+		return
+	}
+
+	var halt int32
+	visit := func(tn *treeNode) bool {
+		if atomic.LoadInt32(&halt) == 1 {
+			return false
+		}
+		if !fn(tn.Node) {
+			atomic.StoreInt32(&halt, 1)
+			return false
+		}
+		return true
+	}
+
+	// split the tree into roughly one subtree per worker
+	subtrees := []*treeNode{ct.root}
+	for len(subtrees) > 0 && len(subtrees) < ct.workers {
+		tn := subtrees[0]
+		subtrees = subtrees[1:]
+		if !visit(tn) {
+			return
+		}
+		if tn.left != nil {
+			subtrees = append(subtrees, tn.left)
+		}
+		if tn.right != nil {
+			subtrees = append(subtrees, tn.right)
+		}
+	}
+
+	wg := new(sync.WaitGroup)
+	wg.Add(len(subtrees))
+	for _, tn := range subtrees {
+		tn := tn
+		ct.jobs <- func() {
+			defer wg.Done()
+			tn.walkPreOrder(visit)
+		}
+	}
+	wg.Wait()
+}
+
+func (ct *ConcurrentTree) work() {
+	for job := range ct.jobs {
+		job()
+	}
+}
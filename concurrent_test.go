@@ -0,0 +1,133 @@
+package gerbst_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestConcurrentTree_SearchFunc(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+
+	ct := gerbst.NewConcurrentTree(4)
+	defer ct.Close()
+	for _, k := range keys {
+		ct.Put(k, k)
+	}
+
+	t.Run("visits_all", func(t *testing.T) {
+		mu := new(sync.Mutex)
+		seen := make(map[uint]int)
+		ct.SearchFunc(func(n *gerbst.Node) bool {
+			mu.Lock()
+			seen[n.Key()]++
+			mu.Unlock()
+			return true
+		})
+		if len(seen) != len(keys) {
+			t.Logf("Expected to visit %d nodes, saw %d", len(keys), len(seen))
+			t.Fail()
+		}
+		for _, k := range keys {
+			if seen[k] != 1 {
+				t.Logf("Expected key %d to be visited once, saw %d", k, seen[k])
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("halts", func(t *testing.T) {
+		var visited int32
+		ct.SearchFunc(func(n *gerbst.Node) bool {
+			atomic.AddInt32(&visited, 1)
+			return false
+		})
+		if v := atomic.LoadInt32(&visited); v < 1 || int(v) > ct.Workers() {
+			t.Logf("Expected between 1 and %d visits after halting, saw %d", ct.Workers(), v)
+			t.Fail()
+		}
+	})
+
+	t.Run("no_goroutine_churn", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		for i := 0; i < 100; i++ {
+			ct.SearchFunc(func(*gerbst.Node) bool { return true })
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Logf("Expected goroutine count to remain at %d after repeated searches, saw %d", before, after)
+			t.Fail()
+		}
+	})
+}
+
+func BenchmarkConcurrentTree_SearchFunc(b *testing.B) {
+	ct := gerbst.NewConcurrentTree(runtime.GOMAXPROCS(0))
+	defer ct.Close()
+	for i := uint(0); i < 10000; i++ {
+		ct.Put((i*7919)%10007, i)
+	}
+
+	before := runtime.NumGoroutine()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ct.SearchFunc(func(*gerbst.Node) bool { return true })
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(runtime.NumGoroutine()-before), "goroutines-added")
+}
+
+// BenchmarkConcurrentTree_SearchFunc_Contended measures SearchFunc with many goroutines searching the same tree at once.
+// Every caller shares the read lock and the worker pool, so this is bounded by the pool rather than by lock contention.
+func BenchmarkConcurrentTree_SearchFunc_Contended(b *testing.B) {
+	ct := gerbst.NewConcurrentTree(runtime.GOMAXPROCS(0))
+	defer ct.Close()
+	for i := uint(0); i < 10000; i++ {
+		ct.Put((i*7919)%10007, i)
+	}
+
+	b.SetParallelism(16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ct.SearchFunc(func(*gerbst.Node) bool { return true })
+		}
+	})
+}
+
+func TestConcurrentTree_Count(t *testing.T) {
+	ct := gerbst.NewConcurrentTree(2)
+	defer ct.Close()
+
+	for i, k := range []uint{12, 11, 90, 12, 82, 7, 9, 7, 90} {
+		ct.Put(k, i)
+	}
+	if c := ct.Count(); c != 6 {
+		t.Logf("Expected duplicate keys not to increment count, saw %d", c)
+		t.Fail()
+	}
+	if c := ct.DuplicateCount(); c != 3 {
+		t.Logf("Expected 3 duplicate puts, saw %d", c)
+		t.Fail()
+	}
+}
+
+func TestNewConcurrentTree_Options(t *testing.T) {
+	ct := gerbst.NewConcurrentTree(2, gerbst.WithDepthBase(gerbst.DepthBaseZero), gerbst.WithInsertLimit(1))
+	defer ct.Close()
+
+	ct.Put(12, 12)
+	if d, ok := ct.Depth(12); !ok || d != 0 {
+		t.Logf("Expected root at depth 0, saw %d", d)
+		t.Fail()
+	}
+	if err := ct.PutErr(11, 11); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,86 @@
+package gerbst
+
+import (
+	"sync"
+)
+
+// CountingTree is a binary search tree that tallies how many times each key has been put, storing the tally as the
+// key's value
+type CountingTree struct {
+	mu sync.RWMutex
+
+	root *treeNode
+
+	total uint
+}
+
+// NewCountingTree constructs a new, empty counting tree
+func NewCountingTree() *CountingTree {
+	ct := new(CountingTree)
+	return ct
+}
+
+// Count returns the number of distinct keys within this tree
+func (ct *CountingTree) Count() uint {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	if ct.root == nil {
+		return 0
+	}
+	return ct.root.count
+}
+
+// Total returns the number of times Put has been called across all keys still present in this tree
+func (ct *CountingTree) Total() uint {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.total
+}
+
+// Put increments the tally of key, creating the key with a tally of 1 if it does not yet exist.  Returns the new tally.
+func (ct *CountingTree) Put(key uint) uint {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.total++
+	if ct.root == nil {
+		ct.root = newTreeNode(key, uint(1), 1, NodeSideRoot, nil, nil, nil)
+		return 1
+	}
+	if tn := ct.root.find(key); tn != nil {
+		c := tn.value.(uint) + 1
+		tn.setValue(c)
+		return c
+	}
+	ct.root.Put(key, uint(1))
+	return 1
+}
+
+// CountOf returns the number of times key has been put, or 0 if it is absent
+func (ct *CountingTree) CountOf(key uint) uint {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	if ct.root == nil || key < ct.root.loKey || key > ct.root.hiKey {
+		return 0
+	}
+	if tn := ct.root.find(key); tn != nil {
+		return tn.value.(uint)
+	}
+	return 0
+}
+
+// Delete removes key along with its tally, returning the tally it held or 0 if the key was absent
+func (ct *CountingTree) Delete(key uint) uint {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.root == nil {
+		return 0
+	}
+	tn := ct.root.find(key)
+	if tn == nil {
+		return 0
+	}
+	c := tn.value.(uint)
+	ct.total -= c
+	deleteNode(&ct.root, tn)
+	return c
+}
@@ -0,0 +1,54 @@
+package gerbst_test
+
+import (
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestCountingTree(t *testing.T) {
+	ct := gerbst.NewCountingTree()
+	for i := uint(1); i <= 3; i++ {
+		if c := ct.Put(11); c != i {
+			t.Logf("Expected put %d to return tally %d, saw %d", i, i, c)
+			t.Fail()
+		}
+	}
+	if c := ct.CountOf(11); c != 3 {
+		t.Logf("Expected key 11 to have been counted 3 times, saw %d", c)
+		t.Fail()
+	}
+	if c := ct.Count(); c != 1 {
+		t.Logf("Expected repeated puts to leave 1 node, saw %d", c)
+		t.Fail()
+	}
+
+	for _, k := range []uint{12, 90, 82, 7, 9, 7} {
+		ct.Put(k)
+	}
+	if c := ct.Count(); c != 6 {
+		t.Logf("Expected 6 distinct keys, saw %d", c)
+		t.Fail()
+	}
+	if c := ct.Total(); c != 9 {
+		t.Logf("Expected 9 puts in total, saw %d", c)
+		t.Fail()
+	}
+	if c := ct.CountOf(50); c != 0 {
+		t.Logf("Expected absent key to have a count of 0, saw %d", c)
+		t.Fail()
+	}
+
+	if c := ct.Delete(7); c != 2 {
+		t.Logf("Expected deleting key 7 to return its tally of 2, saw %d", c)
+		t.Fail()
+	}
+	if c := ct.Total(); c != 7 {
+		t.Logf("Expected 7 puts to remain after delete, saw %d", c)
+		t.Fail()
+	}
+	if c := ct.Delete(7); c != 0 {
+		t.Logf("Expected deleting an absent key to return 0, saw %d", c)
+		t.Fail()
+	}
+}
@@ -0,0 +1,438 @@
+package gerbst
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"strconv"
+)
+
+// ValueEncodeFunc is used in conjunction with LockingTree.Encode to write a single node's value to the output.
+type ValueEncodeFunc = func(w io.Writer, v interface{}) error
+
+// ValueDecodeFunc is used in conjunction with LockingTree.Decode to read a single node's value from the input.  It
+// must consume exactly the bytes written by the matching ValueEncodeFunc.
+type ValueDecodeFunc = func(r io.Reader) (interface{}, error)
+
+// Encode writes the structure of this tree to w, delegating the encoding of each value to encodeValue.
+//
+// The package handles the framing: a big-endian uint64 header laid out as that of MarshalBinary, its high byte the
+// format version and its remaining 56 bits the node count, followed by each node in pre-order as a big-endian uint64
+// key and whatever encodeValue writes for that node's value.  Re-inserting keys in pre-order reproduces the exact
+// shape of the tree, so no child pointers need to be written.  ErrCountOverflow is returned if the count does not fit.
+func (n *LockingTree) Encode(w io.Writer, encodeValue ValueEncodeFunc) error {
+	if n == nil {
+		return NewLockingTree().Encode(w, encodeValue)
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+
+	var count uint
+	if n.root != nil {
+		count = n.root.count
+	}
+	if count > binaryMaxCount {
+		return fmt.Errorf("unable to encode %d nodes, limit is %d: %w", count, uint64(binaryMaxCount), ErrCountOverflow)
+	}
+	if err := binary.Write(w, binary.BigEndian, uint64(binaryVersion())<<binaryVersionShift|uint64(count)); err != nil {
+		return fmt.Errorf("error writing header: %w", err)
+	}
+	if n.root == nil {
+		return nil
+	}
+
+	var err error
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		if err = binary.Write(w, binary.BigEndian, uint64(tn.key)); err != nil {
+			err = fmt.Errorf("error writing key %d: %w", tn.key, err)
+			return false
+		}
+		if err = encodeValue(w, tn.value); err != nil {
+			err = fmt.Errorf("error encoding value for key %d: %w", tn.key, err)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// Decode replaces the contents of this tree with the structure read from r, as written by Encode, delegating the
+// decoding of each value to decodeValue.  The tree is left untouched if an error is returned, including ErrTreeFull if
+// it holds more nodes than the limit set by WithInsertLimit.
+//
+// Input written at an older format version is upgraded as by MigrateTree.  As the nodes are read incrementally, only
+// the header passes through the migration steps.  ErrUnsupportedVersion is returned if the input was written by a
+// newer version than this package supports.
+func (n *LockingTree) Decode(r io.Reader, decodeValue ValueDecodeFunc) error {
+	header := make([]byte, binaryHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("error reading header: %w", err)
+	}
+	header, err := MigrateTree(header)
+	if err != nil {
+		return fmt.Errorf("unable to decode: %w", err)
+	}
+	count := binary.BigEndian.Uint64(header) & binaryMaxCount
+	if n.insertLimit > 0 && count > uint64(n.insertLimit) {
+		return fmt.Errorf("unable to decode %d nodes: %w", count, ErrTreeFull)
+	}
+
+	// build the replacement tree outside of the lock
+	tmp := NewLockingTree(WithDepthBase(n.depthBase))
+	for i := uint64(0); i < count; i++ {
+		var key uint64
+		if err := binary.Read(r, binary.BigEndian, &key); err != nil {
+			return fmt.Errorf("error reading key of node %d: %w", i, err)
+		}
+		value, err := decodeValue(r)
+		if err != nil {
+			return fmt.Errorf("error decoding value for key %d: %w", key, err)
+		}
+		tmp.put(uint(key), value, false)
+	}
+
+	n.lock()
+	defer n.mu.Unlock()
+	n.root = tmp.root
+	n.changed()
+	if n.negCache != nil {
+		n.negCache.clear()
+	}
+	if n.valueIndex != nil {
+		n.valueIndex.reindex(n.root)
+	}
+	return nil
+}
+
+const (
+	binaryHeaderSize = 8
+	binaryRecordSize = 32
+
+	// binaryVersionShift positions the format version within the high byte of the header
+	binaryVersionShift = 56
+	binaryMaxCount     = 1<<binaryVersionShift - 1
+)
+
+// binaryMigrations holds, at each index, the step upgrading a blob of that format version to the next.  The current
+// version is therefore the number of steps.  Decode runs the same steps over the header of its input alone, so each
+// must accept data holding only a header.
+var binaryMigrations = []func(data []byte) ([]byte, error){
+	// version 0 blobs predate versioning, and differ only in leaving the high byte of the header zero
+	func(data []byte) ([]byte, error) { return data, nil },
+}
+
+// binaryVersion returns the format version written by MarshalBinary
+func binaryVersion() byte {
+	return byte(len(binaryMigrations))
+}
+
+// MarshalBinary encodes a tree whose values are all uint into a fixed-layout blob that may be queried in place, for
+// example after being mmap'd, without being unmarshalled.
+//
+// All integers are big-endian uint64.  The blob begins with a header whose high byte is the format version, currently
+// 1, and whose remaining 56 bits are the node count.  It is followed by one 32 byte record per node in pre-order: key,
+// value, left child index, right child index.  The root is always record 0, so an index of 0 means "no child".  A
+// lookup starts at record 0 and follows the child indices exactly as Get follows child pointers.  Blobs written by an
+// older version must be upgraded with MigrateTree before being read.
+//
+// An error is returned if any value is not a uint.
+func (n *LockingTree) MarshalBinary() ([]byte, error) {
+	if n == nil {
+		return NewLockingTree().MarshalBinary()
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+
+	var count uint
+	if n.root != nil {
+		count = n.root.count
+	}
+
+	if count > binaryMaxCount {
+		return nil, fmt.Errorf("unable to encode %d nodes, limit is %d: %w", count, uint64(binaryMaxCount), ErrCountOverflow)
+	}
+	data := make([]byte, binaryHeaderSize+count*binaryRecordSize)
+	binary.BigEndian.PutUint64(data, uint64(binaryVersion())<<binaryVersionShift|uint64(count))
+	if n.root == nil {
+		return data, nil
+	}
+
+	// assign each node its pre-order index up front so parents can reference their children
+	indices := make(map[*treeNode]uint64, count)
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		indices[tn] = uint64(len(indices))
+		return true
+	})
+
+	var err error
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		v, ok := tn.value.(uint)
+		if !ok {
+			err = fmt.Errorf("value for key %d must be uint, saw %T", tn.key, tn.value)
+			return false
+		}
+		rec := data[binaryHeaderSize+indices[tn]*binaryRecordSize:]
+		binary.BigEndian.PutUint64(rec, uint64(tn.key))
+		binary.BigEndian.PutUint64(rec[8:], uint64(v))
+		if tn.left != nil {
+			binary.BigEndian.PutUint64(rec[16:], indices[tn.left])
+		}
+		if tn.right != nil {
+			binary.BigEndian.PutUint64(rec[24:], indices[tn.right])
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// MigrateTree upgrades a blob written by MarshalBinary at any earlier format version to the current version, returning
+// it unchanged if it is already current.  The provided slice is never modified.  Returns ErrUnsupportedVersion if the
+// blob was written by a newer version than this package supports.
+func MigrateTree(data []byte) ([]byte, error) {
+	if len(data) < binaryHeaderSize {
+		return nil, fmt.Errorf("data must be at least %d bytes, saw %d", binaryHeaderSize, len(data))
+	}
+	version := data[0]
+	if version > binaryVersion() {
+		return nil, fmt.Errorf("data is version %d, latest is %d: %w", version, binaryVersion(), ErrUnsupportedVersion)
+	}
+	if version == binaryVersion() {
+		return data, nil
+	}
+	data = append([]byte(nil), data...)
+	for ; version < binaryVersion(); version++ {
+		var err error
+		if data, err = binaryMigrations[version](data); err != nil {
+			return nil, fmt.Errorf("unable to migrate data from version %d: %w", version, err)
+		}
+		data[0] = version + 1
+	}
+	return data, nil
+}
+
+// UnmarshalBinaryTree constructs a new tree from a blob produced by MarshalBinary, preserving its exact shape.  An
+// error is returned if the blob is truncated, references records out of pre-order, leaves records unreachable, or
+// describes a tree that violates binary search tree ordering.  ErrUnsupportedVersion is returned if the blob was not
+// written at the current format version, in which case older blobs may be upgraded with MigrateTree.  The tree is
+// configured with the provided options.  Keys are read as they were written, without normalization, but ErrTreeFull is
+// returned if data holds more nodes than the limit set by WithInsertLimit.
+func UnmarshalBinaryTree(data []byte, opts ...LockingTreeOption) (*LockingTree, error) {
+	if len(data) < binaryHeaderSize {
+		return nil, fmt.Errorf("data must be at least %d bytes, saw %d", binaryHeaderSize, len(data))
+	}
+	if version := data[0]; version != binaryVersion() {
+		return nil, fmt.Errorf("data is version %d, expected %d: %w", version, binaryVersion(), ErrUnsupportedVersion)
+	}
+
+	count := binary.BigEndian.Uint64(data) & binaryMaxCount
+	data = data[binaryHeaderSize:]
+	if count > uint64(len(data))/binaryRecordSize || uint64(len(data)) != count*binaryRecordSize {
+		return nil, fmt.Errorf("header declares %d records but %d bytes of record data follow", count, len(data))
+	}
+
+	lt := NewLockingTree(opts...)
+	if lt.insertLimit > 0 && count > uint64(lt.insertLimit) {
+		return nil, fmt.Errorf("unable to unmarshal %d nodes: %w", count, ErrTreeFull)
+	}
+	if count == 0 {
+		return lt, nil
+	}
+
+	nodes := make([]*treeNode, count)
+	for i := range nodes {
+		rec := data[uint64(i)*binaryRecordSize:]
+		nodes[i] = newTreeNode(
+			uint(binary.BigEndian.Uint64(rec)),
+			uint(binary.BigEndian.Uint64(rec[8:])),
+			0,
+			NodeSideRoot,
+			nil,
+			nil,
+			nil)
+	}
+
+	// link children.  children always follow their parent in pre-order, which also rules out cycles.
+	link := func(parent uint64, child uint64) (*treeNode, error) {
+		if child == 0 {
+			return nil, nil
+		}
+		if child <= parent || child >= count {
+			return nil, fmt.Errorf("record %d references invalid child record %d", parent, child)
+		}
+		if nodes[child].parent != nil {
+			return nil, fmt.Errorf("record %d is referenced by more than one parent", child)
+		}
+		nodes[child].parent = nodes[parent]
+		return nodes[child], nil
+	}
+	for i := uint64(0); i < count; i++ {
+		rec := data[i*binaryRecordSize:]
+		var err error
+		if nodes[i].left, err = link(i, binary.BigEndian.Uint64(rec[16:])); err != nil {
+			return nil, err
+		}
+		if nodes[i].right, err = link(i, binary.BigEndian.Uint64(rec[24:])); err != nil {
+			return nil, err
+		}
+	}
+	for i := uint64(1); i < count; i++ {
+		if nodes[i].parent == nil {
+			return nil, fmt.Errorf("record %d is not reachable from the root", i)
+		}
+	}
+
+	lt.plant(nodes[0])
+	if err := lt.root.validateOrder(); err != nil {
+		return nil, err
+	}
+	return lt, nil
+}
+
+// NewLockingTreeFromReader populates a tree using whitespace-separated keys read from r, streaming them rather than
+// reading all input up front.  The value of each node will be that of the key of that node.  An error is returned
+// upon the first token that is not a valid uint.
+func NewLockingTreeFromReader(r io.Reader, opts ...LockingTreeOption) (*LockingTree, error) {
+	lt := NewLockingTree(opts...)
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for i := 1; scanner.Scan(); i++ {
+		k, err := strconv.ParseUint(scanner.Text(), 10, strconv.IntSize)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing key %d %q: %w", i, scanner.Text(), err)
+		}
+		lt.Put(uint(k), uint(k))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading keys: %w", err)
+	}
+	return lt, nil
+}
+
+// Checksum returns a 64-bit FNV-1a hash of this tree's shape and content, suitable for cheaply detecting change.  Each
+// node is hashed in pre-order as its key followed by its value, which fixes the shape as well as the content: trees
+// holding the same keys in different shapes produce different checksums.  uint values are hashed as their bytes, nil
+// as a single marker byte, and all other values by their %T and %v formatting.  An empty tree has a checksum of 0.
+func (n *LockingTree) Checksum() uint64 {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	h := fnv.New64a()
+	buf := make([]byte, 9)
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		hashKeyValue(h, buf, tn)
+		return true
+	})
+	return h.Sum64()
+}
+
+// hashKeyValue writes the key and value of tn to h as described by Checksum, using buf, which must hold at least 9
+// bytes, as scratch space
+func hashKeyValue(h hash.Hash64, buf []byte, tn *treeNode) {
+	binary.BigEndian.PutUint64(buf, uint64(tn.key))
+	_, _ = h.Write(buf[:8])
+	switch v := tn.value.(type) {
+	case nil:
+		_, _ = h.Write([]byte{0})
+	case uint:
+		buf[0] = 1
+		binary.BigEndian.PutUint64(buf[1:], uint64(v))
+		_, _ = h.Write(buf[:9])
+	default:
+		_, _ = h.Write([]byte{2})
+		_, _ = fmt.Fprintf(h, "%T:%v", v, v)
+	}
+}
+
+// SubtreeHashes returns a Merkle-style hash of every subtree of this tree, keyed by the key of the subtree's root.  Each
+// node's hash is a 64-bit FNV-1a hash of its key and value, hashed as by Checksum, followed by the presence and hash of
+// its left and right branches.  Subtrees of the same shape holding the same keys and values therefore hash equal
+// wherever they appear, regardless of the tree or depth they appear at, while a change to any node alters the hash of
+// every one of its ancestors.  An empty tree returns an empty map.
+func (n *LockingTree) SubtreeHashes() map[uint]uint64 {
+	if n == nil {
+		return map[uint]uint64{}
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return map[uint]uint64{}
+	}
+	hashes := make(map[uint]uint64, n.root.count)
+	h := fnv.New64a()
+	buf := make([]byte, 9)
+	var subtreeHash func(tn *treeNode) uint64
+	subtreeHash = func(tn *treeNode) uint64 {
+		// an absent branch is a single zero byte, a present one a one byte followed by its hash
+		branches := make([]byte, 18)
+		if tn.left != nil {
+			branches[0] = 1
+			binary.BigEndian.PutUint64(branches[1:9], subtreeHash(tn.left))
+		}
+		if tn.right != nil {
+			branches[9] = 1
+			binary.BigEndian.PutUint64(branches[10:], subtreeHash(tn.right))
+		}
+		h.Reset()
+		hashKeyValue(h, buf, tn)
+		_, _ = h.Write(branches)
+		sum := h.Sum64()
+		hashes[tn.key] = sum
+		return sum
+	}
+	subtreeHash(n.root)
+	return hashes
+}
+
+// jsonlRecord is the shape of each line written by WriteJSONL
+type jsonlRecord struct {
+	Key   uint        `json:"key"`
+	Value interface{} `json:"value"`
+	Depth uint        `json:"depth"`
+	Side  string      `json:"side"`
+}
+
+// WriteJSONL writes one JSON object per node to w in ascending key order, each on its own line, in the form of
+// {"key":12,"value":12,"depth":1,"side":"ROOT"}.  Values are encoded with encoding/json, and an error is returned if any
+// value cannot be.  The read lock is held while writing.
+func (n *LockingTree) WriteJSONL(w io.Writer) error {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var err error
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		rec := jsonlRecord{Key: tn.key, Value: tn.value, Depth: tn.depth, Side: tn.side.String()}
+		if err = enc.Encode(rec); err != nil {
+			err = fmt.Errorf("error encoding key %d: %w", tn.key, err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("error flushing output: %w", err)
+	}
+	return nil
+}
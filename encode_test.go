@@ -0,0 +1,435 @@
+package gerbst_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+	"github.com/dcarbone/gerbst/testutil"
+)
+
+func encodeStringValue(w io.Writer, v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("expected string, saw %T", v)
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func decodeStringValue(r io.Reader) (interface{}, error) {
+	var l uint32
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return nil, err
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func TestLockingTree_EncodeDecode(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+
+	src := gerbst.NewLockingTree()
+	for _, k := range keys {
+		src.Put(k, fmt.Sprintf("value-%d", k))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := src.Encode(buf, encodeStringValue); err != nil {
+		t.Logf("Error encoding tree: %v", err)
+		t.FailNow()
+	}
+
+	dst := gerbst.NewLockingTree()
+	if err := dst.Decode(buf, decodeStringValue); err != nil {
+		t.Logf("Error decoding tree: %v", err)
+		t.FailNow()
+	}
+
+	if src.StringTree() != dst.StringTree() {
+		t.Logf("Expected decoded tree to match source")
+		t.Logf("Expected:\n%s", src.StringTree())
+		t.Logf("Actual:\n%s", dst.StringTree())
+		t.Fail()
+	}
+
+	for _, k := range keys {
+		if n, ok := dst.Get(k); !ok {
+			t.Logf("Expected decoded tree to contain key %d", k)
+			t.Fail()
+		} else if v := n.Value(); v != fmt.Sprintf("value-%d", k) {
+			t.Logf("Expected key %d to have value %q, saw %v", k, fmt.Sprintf("value-%d", k), v)
+			t.Fail()
+		}
+	}
+
+	t.Run("versions", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		if err := src.Encode(buf, encodeStringValue); err != nil {
+			t.Logf("Error encoding tree: %v", err)
+			t.FailNow()
+		}
+		data := buf.Bytes()
+		if data[0] != 1 {
+			t.Logf("Expected stream to be written at version 1, saw %d", data[0])
+			t.Fail()
+		}
+
+		// streams written before versioning carried only the count in their header
+		legacy := append([]byte(nil), data...)
+		legacy[0] = 0
+		dst := gerbst.NewLockingTree()
+		if err := dst.Decode(bytes.NewReader(legacy), decodeStringValue); err != nil || dst.StringTree() != src.StringTree() {
+			t.Logf("Expected unversioned stream to decode to the source tree, saw %v", err)
+			t.Fail()
+		}
+
+		future := append([]byte(nil), data...)
+		future[0] = 9
+		if err := dst.Decode(bytes.NewReader(future), decodeStringValue); !errors.Is(err, gerbst.ErrUnsupportedVersion) {
+			t.Logf("Expected stream from a newer version to be rejected, saw %v", err)
+			t.Fail()
+		}
+		if c := dst.Count(); c != 6 {
+			t.Logf("Expected rejected stream to leave the tree untouched, saw count %d", c)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_MarshalBinary(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	src := gerbst.NewLockingTreeWithKeys(keys)
+
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Logf("Error marshalling tree: %v", err)
+		t.FailNow()
+	}
+	if l := len(data); l != 8+len(keys)*32 {
+		t.Logf("Expected %d bytes, saw %d", 8+len(keys)*32, l)
+		t.Fail()
+	}
+
+	dst, err := gerbst.UnmarshalBinaryTree(data)
+	if err != nil {
+		t.Logf("Error unmarshalling tree: %v", err)
+		t.FailNow()
+	}
+
+	if src.StringTree() != dst.StringTree() {
+		t.Log("Expected unmarshalled tree to match source")
+		t.Logf("Expected:\n%s", src.StringTree())
+		t.Logf("Actual:\n%s", dst.StringTree())
+		t.Fail()
+	}
+
+	t.Run("counts", testutil.BuildTestCounts(dst, false, 6, 3, 2))
+	t.Run("depths", testutil.BuildTestDepths(dst, false, 4, 4, 3))
+	t.Run("gets", testutil.BuildTestGets(dst, false, testutil.GetTestsFromKeys(keys, []uint{0, 83, 100})))
+
+	t.Run("options", func(t *testing.T) {
+		zdst, err := gerbst.UnmarshalBinaryTree(data, gerbst.WithDepthBase(gerbst.DepthBaseZero), gerbst.WithValueIndex())
+		if err != nil {
+			t.Logf("Error unmarshalling tree: %v", err)
+			t.FailNow()
+		}
+		if d, ok := zdst.Depth(12); !ok || d != 0 {
+			t.Logf("Expected root key 12 at depth 0, saw %d", d)
+			t.Fail()
+		}
+		if keys := zdst.KeysForValue(uint(82)); len(keys) != 1 || keys[0] != 82 {
+			t.Logf("Expected value index to hold key 82, saw %v", keys)
+			t.Fail()
+		}
+	})
+
+	t.Run("insert_limit", func(t *testing.T) {
+		if _, err := gerbst.UnmarshalBinaryTree(data, gerbst.WithInsertLimit(5)); !errors.Is(err, gerbst.ErrTreeFull) {
+			t.Logf("Expected ErrTreeFull unmarshalling more nodes than the insert limit, saw %v", err)
+			t.Fail()
+		}
+		if lt, err := gerbst.UnmarshalBinaryTree(data, gerbst.WithInsertLimit(6)); err != nil || lt.Count() != 6 {
+			t.Logf("Expected nodes within the insert limit to be unmarshalled, saw %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("non_uint_value", func(t *testing.T) {
+		lt := gerbst.NewLockingTree()
+		lt.Put(1, "one")
+		if _, err := lt.MarshalBinary(); err == nil {
+			t.Log("Expected error marshalling non-uint value")
+			t.Fail()
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		if _, err := gerbst.UnmarshalBinaryTree(data[:len(data)-1]); err == nil {
+			t.Log("Expected error unmarshalling truncated data")
+			t.Fail()
+		}
+
+		// swap the keys of the root and its left child, breaking ordering
+		bad := append([]byte(nil), data...)
+		binary.BigEndian.PutUint64(bad[8:], 11)
+		binary.BigEndian.PutUint64(bad[8+32:], 12)
+		if _, err := gerbst.UnmarshalBinaryTree(bad); err == nil {
+			t.Log("Expected error unmarshalling out-of-order keys")
+			t.Fail()
+		}
+	})
+}
+
+func TestMigrateTree(t *testing.T) {
+	src := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Logf("Error marshalling tree: %v", err)
+		t.FailNow()
+	}
+	if data[0] != 1 {
+		t.Logf("Expected data to be written at version 1, saw %d", data[0])
+		t.Fail()
+	}
+	if current, err := gerbst.MigrateTree(data); err != nil || !bytes.Equal(current, data) {
+		t.Logf("Expected current data to be returned unchanged, saw %v", err)
+		t.Fail()
+	}
+
+	// blobs written before versioning carried only the count in their header
+	legacy := append([]byte(nil), data...)
+	legacy[0] = 0
+	if _, err := gerbst.UnmarshalBinaryTree(legacy); !errors.Is(err, gerbst.ErrUnsupportedVersion) {
+		t.Logf("Expected unversioned data to require migration, saw %v", err)
+		t.Fail()
+	}
+	migrated, err := gerbst.MigrateTree(legacy)
+	if err != nil {
+		t.Logf("Error migrating unversioned data: %v", err)
+		t.FailNow()
+	}
+	if legacy[0] != 0 {
+		t.Log("Expected migration to leave its input untouched")
+		t.Fail()
+	}
+	if dst, err := gerbst.UnmarshalBinaryTree(migrated); err != nil || dst.StringTree() != src.StringTree() {
+		t.Logf("Expected migrated data to decode to the source tree, saw %v", err)
+		t.Fail()
+	}
+
+	future := append([]byte(nil), data...)
+	future[0] = 9
+	if _, err := gerbst.MigrateTree(future); !errors.Is(err, gerbst.ErrUnsupportedVersion) {
+		t.Logf("Expected data from a newer version to be rejected, saw %v", err)
+		t.Fail()
+	}
+	if _, err := gerbst.MigrateTree(data[:4]); err == nil {
+		t.Log("Expected truncated header to be rejected")
+		t.Fail()
+	}
+}
+
+func TestNewLockingTreeFromReader(t *testing.T) {
+	lt, err := gerbst.NewLockingTreeFromReader(strings.NewReader("12 11\n90\t82\n\n  7 9\n"))
+	if err != nil {
+		t.Logf("Unexpected error: %v", err)
+		t.FailNow()
+	}
+	if st, expected := lt.StringTree(), gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}).StringTree(); st != expected {
+		t.Log("Tree did not match expected")
+		t.Logf("Expected:\n%s", expected)
+		t.Logf("Actual:\n%s", st)
+		t.Fail()
+	}
+
+	if _, err := gerbst.NewLockingTreeFromReader(strings.NewReader("12 11 ninety 82")); err == nil {
+		t.Log("Expected error for non-numeric token")
+		t.Fail()
+	}
+	if _, err := gerbst.NewLockingTreeFromReader(strings.NewReader("12 -1")); err == nil {
+		t.Log("Expected error for negative token")
+		t.Fail()
+	}
+
+	if lt, err := gerbst.NewLockingTreeFromReader(strings.NewReader("")); err != nil || lt.Count() != 0 {
+		t.Logf("Expected empty input to produce empty tree, saw %v", err)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Checksum(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+
+	sum := lt.Checksum()
+	if sum == 0 {
+		t.Log("Expected non-empty tree to have a non-zero checksum")
+		t.Fail()
+	}
+	if again := lt.Checksum(); again != sum {
+		t.Logf("Expected checksum to be stable, saw %d then %d", sum, again)
+		t.Fail()
+	}
+	if other := gerbst.NewLockingTreeWithKeys(keys).Checksum(); other != sum {
+		t.Logf("Expected identically built tree to share checksum %d, saw %d", sum, other)
+		t.Fail()
+	}
+
+	lt.Put(82, uint(83))
+	if changed := lt.Checksum(); changed == sum {
+		t.Log("Expected a single value change to alter the checksum")
+		t.Fail()
+	}
+	lt.Put(82, uint(82))
+	if restored := lt.Checksum(); restored != sum {
+		t.Logf("Expected restoring the value to restore checksum %d, saw %d", sum, restored)
+		t.Fail()
+	}
+
+	if shaped := gerbst.NewBalancedTreeWithSortedKeys([]uint{7, 9, 11, 12, 82, 90}).Checksum(); shaped == sum {
+		t.Log("Expected a differently shaped tree with the same keys and values to have a different checksum")
+		t.Fail()
+	}
+
+	if typed := gerbst.NewLockingTreeWithKeysValue(keys, "x").Checksum(); typed == gerbst.NewLockingTreeWithKeysValue(keys, "y").Checksum() {
+		t.Log("Expected non-uint values to contribute to the checksum")
+		t.Fail()
+	}
+
+	if empty := gerbst.NewLockingTree().Checksum(); empty != 0 {
+		t.Logf("Expected empty tree to have checksum 0, saw %d", empty)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_WriteJSONL(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	buf := new(bytes.Buffer)
+	if err := lt.WriteJSONL(buf); err != nil {
+		t.Logf("Expected JSONL to be written, saw %v", err)
+		t.FailNow()
+	}
+
+	type record struct {
+		Key   uint   `json:"key"`
+		Value uint   `json:"value"`
+		Depth uint   `json:"depth"`
+		Side  string `json:"side"`
+	}
+	expected := []record{
+		{Key: 7, Value: 7, Depth: 3, Side: "LEFT"},
+		{Key: 9, Value: 9, Depth: 4, Side: "RIGHT"},
+		{Key: 11, Value: 11, Depth: 2, Side: "LEFT"},
+		{Key: 12, Value: 12, Depth: 1, Side: "ROOT"},
+		{Key: 82, Value: 82, Depth: 3, Side: "LEFT"},
+		{Key: 90, Value: 90, Depth: 2, Side: "RIGHT"},
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if uint(len(lines)) != lt.Count() {
+		t.Logf("Expected %d lines, saw %d:\n%s", lt.Count(), len(lines), buf.String())
+		t.FailNow()
+	}
+	for i, line := range lines {
+		var rec record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Logf("Expected line %d to be valid JSON, saw %v: %s", i, err, line)
+			t.Fail()
+			continue
+		}
+		if rec != expected[i] {
+			t.Logf("Expected line %d to be %+v, saw %+v", i, expected[i], rec)
+			t.Fail()
+		}
+	}
+
+	t.Run("unencodable", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeysValue([]uint{1, 2}, func() {})
+		if err := lt.WriteJSONL(io.Discard); err == nil {
+			t.Log("Expected unencodable value to return an error")
+			t.Fail()
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		if err := gerbst.NewLockingTree().WriteJSONL(buf); err != nil || buf.Len() != 0 {
+			t.Logf("Expected empty tree to write nothing, saw %q (%v)", buf.String(), err)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_SubtreeHashes(t *testing.T) {
+	a := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	// the subtree rooted at 11 is identical in shape and content, but sits beneath a different root and depth
+	b := gerbst.NewLockingTreeWithKeys([]uint{50, 40, 11, 7, 9, 60})
+
+	ha, hb := a.SubtreeHashes(), b.SubtreeHashes()
+	if uint(len(ha)) != a.Count() || uint(len(hb)) != b.Count() {
+		t.Logf("Expected a hash per node, saw %d and %d", len(ha), len(hb))
+		t.Fail()
+	}
+	for _, k := range []uint{11, 7, 9} {
+		if ha[k] != hb[k] {
+			t.Logf("Expected shared subtree at key %d to hash equal, saw %d and %d", k, ha[k], hb[k])
+			t.Fail()
+		}
+	}
+	if ha[12] == hb[50] {
+		t.Log("Expected differing trees to have differing root hashes")
+		t.Fail()
+	}
+
+	// 82 is a leaf in both, but 90 has a right child in c
+	c := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9, 100})
+	hc := c.SubtreeHashes()
+	if hc[82] != ha[82] || hc[11] != ha[11] {
+		t.Log("Expected untouched subtrees to keep their hashes")
+		t.Fail()
+	}
+	if hc[90] == ha[90] || hc[12] == ha[12] {
+		t.Log("Expected an insertion to alter the hash of each ancestor")
+		t.Fail()
+	}
+
+	// a value change alters its own subtree and every ancestor, but no sibling
+	a.Put(9, "nine")
+	changed := a.SubtreeHashes()
+	for _, k := range []uint{9, 7, 11, 12} {
+		if changed[k] == ha[k] {
+			t.Logf("Expected value change to alter hash of key %d", k)
+			t.Fail()
+		}
+	}
+	if changed[90] != ha[90] {
+		t.Log("Expected value change to leave a sibling subtree's hash unchanged")
+		t.Fail()
+	}
+
+	// the same keys in mirrored shapes
+	left := gerbst.NewLockingTreeWithKeys([]uint{9, 7}).SubtreeHashes()
+	right := gerbst.NewLockingTreeWithKeys([]uint{7, 9}).SubtreeHashes()
+	if left[7] == right[7] || left[9] == right[9] {
+		t.Log("Expected differently shaped subtrees to hash differently")
+		t.Fail()
+	}
+
+	if h := gerbst.NewLockingTree().SubtreeHashes(); len(h) != 0 {
+		t.Logf("Expected empty tree to have no hashes, saw %v", h)
+		t.Fail()
+	}
+}
@@ -0,0 +1,133 @@
+package gerbst
+
+import (
+	"fmt"
+)
+
+// FlatNode is a self-contained record of a single node and its position within a tree, as produced by Flatten
+type FlatNode struct {
+	Key       uint
+	Value     interface{}
+	Depth     uint
+	Side      NodeSide
+	ParentKey uint
+	HasParent bool
+}
+
+// Flatten returns a record of every node in this tree in ascending key order, each carrying its depth, side, and the
+// key of its parent.  The root is the only record with HasParent set to false.
+func (n *LockingTree) Flatten() []FlatNode {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+	flat := make([]FlatNode, 0, n.root.count)
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		fn := FlatNode{
+			Key:   tn.key,
+			Value: tn.value,
+			Depth: tn.depth,
+			Side:  tn.side,
+		}
+		if tn.parent != nil {
+			fn.ParentKey = tn.parent.key
+			fn.HasParent = true
+		}
+		flat = append(flat, fn)
+		return true
+	})
+	return flat
+}
+
+// FromFlat constructs a new tree with exactly the shape described by the provided records, in any order, such as those
+// returned by Flatten.  The depth of the root record determines the tree's depth base, and must be 0 or 1.
+//
+// An error is returned if a key appears more than once, there is not exactly one root, a record references a parent
+// that is not present, two records claim the same side of a parent, a record is not reachable from the root, a
+// record's depth does not follow from its parent's, or the resulting tree violates binary search tree ordering.
+func FromFlat(nodes []FlatNode) (*LockingTree, error) {
+	if len(nodes) == 0 {
+		return NewLockingTree(), nil
+	}
+
+	var root *FlatNode
+	tns := make(map[uint]*treeNode, len(nodes))
+	for i := range nodes {
+		fn := &nodes[i]
+		if _, ok := tns[fn.Key]; ok {
+			return nil, fmt.Errorf("key %d appears more than once", fn.Key)
+		}
+		if !fn.HasParent {
+			if root != nil {
+				return nil, fmt.Errorf("keys %d and %d both claim to be the root", root.Key, fn.Key)
+			}
+			if fn.Depth > 1 {
+				return nil, fmt.Errorf("root key %d has depth %d, expected 0 or 1", fn.Key, fn.Depth)
+			}
+			root = fn
+		}
+		tns[fn.Key] = newTreeNode(fn.Key, fn.Value, fn.Depth, fn.Side, nil, nil, nil)
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no key claims to be the root")
+	}
+
+	// link children
+	for i := range nodes {
+		fn := &nodes[i]
+		if !fn.HasParent {
+			continue
+		}
+		parent, ok := tns[fn.ParentKey]
+		if !ok {
+			return nil, fmt.Errorf("key %d references absent parent key %d", fn.Key, fn.ParentKey)
+		}
+		tn := tns[fn.Key]
+		switch fn.Side {
+		case NodeSideLeft:
+			if parent.left != nil {
+				return nil, fmt.Errorf("keys %d and %d both claim the left side of key %d", parent.left.key, fn.Key, parent.key)
+			}
+			parent.left = tn
+		case NodeSideRight:
+			if parent.right != nil {
+				return nil, fmt.Errorf("keys %d and %d both claim the right side of key %d", parent.right.key, fn.Key, parent.key)
+			}
+			parent.right = tn
+		default:
+			return nil, fmt.Errorf("key %d has parent key %d but side %s", fn.Key, fn.ParentKey, fn.Side)
+		}
+		tn.parent = parent
+	}
+
+	// records that form a cycle apart from the root are never reached
+	var reached int
+	var err error
+	tns[root.Key].walkPreOrder(func(tn *treeNode) bool {
+		reached++
+		if tn.parent != nil && tn.depth != tn.parent.depth+1 {
+			err = fmt.Errorf("key %d has depth %d beneath key %d at depth %d", tn.key, tn.depth, tn.parent.key, tn.parent.depth)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reached != len(nodes) {
+		return nil, fmt.Errorf("%d of %d keys are not reachable from root key %d", len(nodes)-reached, len(nodes), root.Key)
+	}
+
+	lt := NewLockingTree()
+	if root.Depth == 0 {
+		lt.depthBase = DepthBaseZero
+	}
+	lt.plant(tns[root.Key])
+	if err := lt.root.validateOrder(); err != nil {
+		return nil, err
+	}
+	return lt, nil
+}
@@ -0,0 +1,132 @@
+package gerbst_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestLockingTree_Flatten(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	type position struct {
+		parent    uint
+		hasParent bool
+		depth     uint
+		side      gerbst.NodeSide
+	}
+	expected := map[uint]position{
+		12: {depth: 1, side: gerbst.NodeSideRoot},
+		11: {parent: 12, hasParent: true, depth: 2, side: gerbst.NodeSideLeft},
+		90: {parent: 12, hasParent: true, depth: 2, side: gerbst.NodeSideRight},
+		7:  {parent: 11, hasParent: true, depth: 3, side: gerbst.NodeSideLeft},
+		82: {parent: 90, hasParent: true, depth: 3, side: gerbst.NodeSideLeft},
+		9:  {parent: 7, hasParent: true, depth: 4, side: gerbst.NodeSideRight},
+	}
+
+	flat := lt.Flatten()
+	if len(flat) != len(expected) {
+		t.Logf("Expected %d flattened records, saw %d", len(expected), len(flat))
+		t.FailNow()
+	}
+	for i, fn := range flat {
+		if i > 0 && flat[i-1].Key >= fn.Key {
+			t.Logf("Expected records in ascending key order, saw %d before %d", flat[i-1].Key, fn.Key)
+			t.Fail()
+		}
+		pos, ok := expected[fn.Key]
+		if !ok {
+			t.Logf("Saw unexpected key %d", fn.Key)
+			t.Fail()
+			continue
+		}
+		if fn.Value != fn.Key {
+			t.Logf("Expected key %d to hold its own key as value, saw %v", fn.Key, fn.Value)
+			t.Fail()
+		}
+		if fn.ParentKey != pos.parent || fn.HasParent != pos.hasParent {
+			t.Logf("Expected key %d to have parent %d (%t), saw %d (%t)", fn.Key, pos.parent, pos.hasParent, fn.ParentKey, fn.HasParent)
+			t.Fail()
+		}
+		if fn.Depth != pos.depth || fn.Side != pos.side {
+			t.Logf("Expected key %d at depth %d side %s, saw depth %d side %s", fn.Key, pos.depth, pos.side, fn.Depth, fn.Side)
+			t.Fail()
+		}
+	}
+
+	if flat := gerbst.NewLockingTree().Flatten(); len(flat) != 0 {
+		t.Logf("Expected empty tree to flatten to no records, saw %d", len(flat))
+		t.Fail()
+	}
+}
+
+func TestFromFlat(t *testing.T) {
+	t.Run("round_trip", func(t *testing.T) {
+		for _, opts := range [][]gerbst.LockingTreeOption{nil, {gerbst.WithDepthBase(gerbst.DepthBaseZero)}} {
+			lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}, opts...)
+			flat := lt.Flatten()
+			rt, err := gerbst.FromFlat(flat)
+			if err != nil {
+				t.Logf("Expected flattened tree to rebuild, saw %v", err)
+				t.Fail()
+				continue
+			}
+			if err := rt.SelfCheck(); err != nil {
+				t.Logf("Expected rebuilt tree to pass self check, saw %v", err)
+				t.Fail()
+			}
+			if !rt.EqualSet(lt) {
+				t.Log("Expected rebuilt tree to hold the same keys and values")
+				t.Fail()
+			}
+			if rf := rt.Flatten(); !reflect.DeepEqual(rf, flat) {
+				t.Logf("Expected rebuilt tree to have the same shape\nexpected: %v\nsaw:      %v", flat, rf)
+				t.Fail()
+			}
+			if rt.StringTree() != lt.StringTree() {
+				t.Logf("Expected rebuilt tree to print identically\nexpected:\n%s\nsaw:\n%s", lt.StringTree(), rt.StringTree())
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if lt, err := gerbst.FromFlat(nil); err != nil || lt.Count() != 0 {
+			t.Logf("Expected no records to rebuild an empty tree, saw %d keys and %v", lt.Count(), err)
+			t.Fail()
+		}
+	})
+
+	corrupt := map[string]func(flat []gerbst.FlatNode){
+		"absent_parent": func(flat []gerbst.FlatNode) { flat[0].ParentKey = 1000 },
+		"no_root": func(flat []gerbst.FlatNode) {
+			for i := range flat {
+				if !flat[i].HasParent {
+					flat[i].HasParent = true
+					flat[i].ParentKey = 9
+					flat[i].Side = gerbst.NodeSideRight
+				}
+			}
+		},
+		"two_roots":     func(flat []gerbst.FlatNode) { flat[0].HasParent = false },
+		"duplicate_key": func(flat []gerbst.FlatNode) { flat[1].Key = flat[0].Key },
+		"side_taken":    func(flat []gerbst.FlatNode) { flat[2].ParentKey, flat[2].Side = 11, gerbst.NodeSideLeft },
+		"cycle": func(flat []gerbst.FlatNode) {
+			// 7 is the parent of 9, making 9 the parent of 7 detaches both from the root
+			flat[0].ParentKey, flat[0].Side = 9, gerbst.NodeSideLeft
+		},
+		"depth":    func(flat []gerbst.FlatNode) { flat[0].Depth = 7 },
+		"ordering": func(flat []gerbst.FlatNode) { flat[1].Side = gerbst.NodeSideLeft },
+	}
+	for name, fn := range corrupt {
+		t.Run(name, func(t *testing.T) {
+			flat := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}).Flatten()
+			fn(flat)
+			if lt, err := gerbst.FromFlat(flat); err == nil {
+				t.Logf("Expected corrupted records to be rejected, saw tree:\n%s", lt.StringTree())
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package gerbst
+
+// FrozenTree is an immutable copy of a LockingTree, as returned by Freeze.  As it can never change it requires no
+// locking, and every method is safe for concurrent use.
+type FrozenTree struct {
+	root *treeNode
+}
+
+// Freeze returns an immutable copy of this tree.  Later changes to the receiver are not reflected in the copy.
+//
+// The copy is laid out in a single block in breadth-first order, with each node's key and value alongside its links,
+// so that the upper levels visited by every lookup share as few cache lines as possible.
+func (n *LockingTree) Freeze() *FrozenTree {
+	ft := new(FrozenTree)
+	if n == nil {
+		return ft
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return ft
+	}
+
+	type cell struct {
+		tn   treeNode
+		node Node
+	}
+	type queued struct {
+		src    *treeNode
+		parent *treeNode // the copy of src's parent
+	}
+	cells := make([]cell, n.root.count)
+	queue := make([]queued, 1, n.root.count)
+	queue[0] = queued{src: n.root}
+	for i := 0; i < len(queue); i++ {
+		q := queue[i]
+		c := &cells[i]
+		c.node = *q.src.Node
+		c.tn = *q.src
+		c.tn.Node = &c.node
+		c.tn.parent, c.tn.left, c.tn.right = q.parent, nil, nil
+		if q.parent != nil {
+			if q.src.side == NodeSideLeft {
+				q.parent.left = &c.tn
+			} else {
+				q.parent.right = &c.tn
+			}
+		}
+		if q.src.left != nil {
+			queue = append(queue, queued{src: q.src.left, parent: &c.tn})
+		}
+		if q.src.right != nil {
+			queue = append(queue, queued{src: q.src.right, parent: &c.tn})
+		}
+	}
+	ft.root = &cells[0].tn
+	return ft
+}
+
+// Count returns the total number of nodes within this tree
+func (ft *FrozenTree) Count() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.count
+}
+
+// CountLeft returns the total number of nodes on the left side of this tree
+func (ft *FrozenTree) CountLeft() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.countLeft
+}
+
+// CountRight returns the total number of nodes on the right side of this tree
+func (ft *FrozenTree) CountRight() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.countRight
+}
+
+// DepthMax returns the depth of the deepest node, or 0 if this tree is empty
+func (ft *FrozenTree) DepthMax() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.depthMax
+}
+
+// DepthMaxLeft returns the depth of the deepest node in the left branch, or 0 if there is no left branch
+func (ft *FrozenTree) DepthMaxLeft() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.depthMaxLeft
+}
+
+// DepthMaxRight returns the depth of the deepest node in the right branch, or 0 if there is no right branch
+func (ft *FrozenTree) DepthMaxRight() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.depthMaxRight
+}
+
+// LowestKey returns the smallest key in this tree
+func (ft *FrozenTree) LowestKey() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.loKey
+}
+
+// HighestKey returns the highest key in this tree
+func (ft *FrozenTree) HighestKey() uint {
+	if ft == nil || ft.root == nil {
+		return 0
+	}
+	return ft.root.hiKey
+}
+
+// Get attempts to retrieve a node by key
+func (ft *FrozenTree) Get(key uint) (*Node, bool) {
+	if ft == nil || ft.root == nil || key < ft.root.loKey || key > ft.root.hiKey {
+		return nil, false
+	}
+	return ft.root.Get(key)
+}
+
+// Range returns every node whose key lies within [lo, hi] in ascending key order
+func (ft *FrozenTree) Range(lo, hi uint) []*Node {
+	if ft == nil || ft.root == nil || lo > hi {
+		return nil
+	}
+	var nodes []*Node
+	ft.root.walkRange(lo, hi, func(tn *treeNode) bool {
+		nodes = append(nodes, tn.Node)
+		return true
+	})
+	return nodes
+}
+
+// InOrder calls fn with each node of this tree in ascending key order, halting when fn returns false
+func (ft *FrozenTree) InOrder(fn func(*Node) bool) {
+	if ft == nil || ft.root == nil {
+		return
+	}
+	ft.root.walkInOrder(func(tn *treeNode) bool {
+		return fn(tn.Node)
+	})
+}
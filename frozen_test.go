@@ -0,0 +1,111 @@
+package gerbst_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestLockingTree_Freeze(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	ft := lt.Freeze()
+
+	lt.Put(50, 50)
+	lt.Delete(9)
+
+	if c := ft.Count(); c != 6 {
+		t.Logf("Expected frozen tree to keep 6 nodes after the original changed, saw %d", c)
+		t.Fail()
+	}
+	if _, ok := ft.Get(50); ok {
+		t.Log("Expected key put after freezing not to be found")
+		t.Fail()
+	}
+	if n, ok := ft.Get(9); !ok || n.Depth() != 4 {
+		t.Logf("Expected key deleted after freezing to be found at depth 4, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		stats := []uint{ft.Count(), ft.CountLeft(), ft.CountRight(), ft.DepthMax(), ft.DepthMaxLeft(), ft.DepthMaxRight(), ft.LowestKey(), ft.HighestKey()}
+		if s := fmt.Sprint(stats); s != "[6 3 2 4 4 3 7 90]" {
+			t.Logf("Expected frozen stats [6 3 2 4 4 3 7 90], saw %s", s)
+			t.Fail()
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		var keys []uint
+		for _, n := range ft.Range(8, 82) {
+			keys = append(keys, n.Key())
+		}
+		if s := fmt.Sprint(keys); s != "[9 11 12 82]" {
+			t.Logf("Expected keys [9 11 12 82] within [8, 82], saw %s", s)
+			t.Fail()
+		}
+		if r := ft.Range(13, 81); len(r) != 0 {
+			t.Logf("Expected no keys within [13, 81], saw %d", len(r))
+			t.Fail()
+		}
+	})
+
+	t.Run("in_order", func(t *testing.T) {
+		var keys []uint
+		ft.InOrder(func(n *gerbst.Node) bool {
+			keys = append(keys, n.Key())
+			return n.Key() < 12
+		})
+		if s := fmt.Sprint(keys); s != "[7 9 11 12]" {
+			t.Logf("Expected in-order walk to halt after key 12, saw %s", s)
+			t.Fail()
+		}
+	})
+
+	if c := gerbst.NewLockingTree().Freeze().Count(); c != 0 {
+		t.Logf("Expected frozen empty tree to be empty, saw %d", c)
+		t.Fail()
+	}
+}
+
+// benchmarkFrozenKeys returns the keys to build a tree from, along with the same keys shuffled to look them up in, so
+// that lookups do not follow the order nodes were allocated in
+func benchmarkFrozenKeys() ([]uint, []uint) {
+	rng := rand.New(rand.NewSource(179))
+	keys := make([]uint, 100000)
+	for i, k := range rng.Perm(len(keys)) {
+		keys[i] = uint(k)
+	}
+	lookups := make([]uint, len(keys))
+	for i, k := range rng.Perm(len(keys)) {
+		lookups[i] = uint(k)
+	}
+	return keys, lookups
+}
+
+func BenchmarkLockingTree_Get_Parallel(b *testing.B) {
+	keys, lookups := benchmarkFrozenKeys()
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			lt.Get(lookups[i%len(lookups)])
+			i++
+		}
+	})
+}
+
+func BenchmarkFrozenTree_Get_Parallel(b *testing.B) {
+	keys, lookups := benchmarkFrozenKeys()
+	ft := gerbst.NewLockingTreeWithKeys(keys).Freeze()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			ft.Get(lookups[i%len(lookups)])
+			i++
+		}
+	})
+}
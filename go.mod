@@ -1,5 +1,5 @@
 module github.com/dcarbone/gerbst
 
-go 1.16
+go 1.18
 
 require github.com/disiqueira/gotree v1.0.0
@@ -0,0 +1,93 @@
+package gerbst
+
+// Iterator steps through the nodes of a LockingTree in ascending key order without holding the tree's lock between
+// steps.  Iterators are not safe for concurrent use.
+//
+// Inserting or deleting a node after an iterator has been created invalidates it, and the next call to Next will
+// panic rather than silently return incorrect results.  Updating the value of an existing key does not.
+type Iterator struct {
+	tree *LockingTree
+	next *treeNode
+	mods uint64
+}
+
+// Iterator returns a new iterator positioned before the lowest key in this tree
+func (n *LockingTree) Iterator() *Iterator {
+	if n == nil {
+		return new(Iterator)
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	it := new(Iterator)
+	it.tree = n
+	it.mods = n.mods
+	if n.root != nil {
+		it.next = n.root.lowest()
+	}
+	return it
+}
+
+// Next returns the next node in ascending key order, or false once every node has been returned.  Panics if the tree
+// has been modified since this iterator was created.
+func (it *Iterator) Next() (*Node, bool) {
+	if it.tree == nil {
+		return nil, false
+	}
+	it.tree.rlock()
+	defer it.tree.mu.RUnlock()
+	if it.tree.mods != it.mods {
+		panic("gerbst: tree modified during iteration")
+	}
+	if it.next == nil {
+		return nil, false
+	}
+	tn := it.next
+	it.next = tn.successor()
+	return tn.Node, true
+}
+
+// LiveCursor steps through the nodes of a LockingTree in ascending key order, tolerating changes made between steps.
+// Each call to Next holds the read lock only for that step, and seeks afresh for the lowest key greater than the one
+// last returned.  LiveCursors are not safe for concurrent use.
+//
+// The cursor observes the tree as it is at each step rather than as it was when the cursor was created: keys inserted
+// ahead of the cursor are returned, keys deleted before it reaches them are not, and keys inserted behind it are never
+// returned.  Keys are always returned in strictly ascending order, and no key is returned twice.
+type LiveCursor struct {
+	tree *LockingTree
+	next uint
+	done bool
+}
+
+// LiveCursor returns a new cursor whose first call to Next returns the node with the lowest key greater than or equal
+// to start
+func (n *LockingTree) LiveCursor(start uint) *LiveCursor {
+	lc := new(LiveCursor)
+	lc.tree = n
+	lc.next = start
+	lc.done = n == nil
+	return lc
+}
+
+// Next returns the node with the lowest key not yet passed by this cursor, or false if there is none.  Each seek is
+// O(height).  After returning false, Next may be called again to pick up keys since inserted ahead of the cursor.
+func (lc *LiveCursor) Next() (*Node, bool) {
+	if lc.done {
+		return nil, false
+	}
+	lc.tree.rlock()
+	defer lc.tree.mu.RUnlock()
+	var tn *treeNode
+	if lc.tree.root != nil {
+		tn = lc.tree.root.ceiling(lc.next)
+	}
+	if tn == nil {
+		return nil, false
+	}
+	// the highest possible key leaves nothing further to seek
+	if tn.key == ^uint(0) {
+		lc.done = true
+	}
+	lc.next = tn.key + 1
+	return tn.Node, true
+}
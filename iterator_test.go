@@ -0,0 +1,106 @@
+package gerbst_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestIterator(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	t.Run("ordered", func(t *testing.T) {
+		it := lt.Iterator()
+		keys := make([]uint, 0)
+		for n, ok := it.Next(); ok; n, ok = it.Next() {
+			keys = append(keys, n.Key())
+		}
+		if !equalKeys(keys, []uint{7, 9, 11, 12, 82, 90}) {
+			t.Logf("Expected keys in ascending order, saw %v", keys)
+			t.Fail()
+		}
+	})
+
+	t.Run("value_update", func(t *testing.T) {
+		it := lt.Iterator()
+		it.Next()
+		lt.Put(82, "updated")
+		var last *gerbst.Node
+		for n, ok := it.Next(); ok; n, ok = it.Next() {
+			last = n
+		}
+		if last == nil || last.Key() != 90 {
+			t.Logf("Expected value updates not to disturb iteration, saw last node %v", last)
+			t.Fail()
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		it := lt.Iterator()
+		it.Next()
+		lt.Put(50, 50)
+		defer func() {
+			if recover() == nil {
+				t.Log("Expected Next to panic after the tree was modified")
+				t.Fail()
+			}
+		}()
+		it.Next()
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if _, ok := gerbst.NewLockingTree().Iterator().Next(); ok {
+			t.Log("Expected iterator over empty tree to be exhausted")
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_LiveCursor(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	lc := lt.LiveCursor(10)
+
+	var seen []uint
+	next := func() {
+		if n, ok := lc.Next(); ok {
+			seen = append(seen, n.Key())
+		}
+	}
+
+	next()         // 11
+	lt.Put(50, 50) // ahead of the cursor, must be visited
+	lt.Put(10, 10) // behind the cursor, must not be visited
+	lt.Delete(82)  // ahead of the cursor, must not be visited
+	for i := 0; i < 10; i++ {
+		next()
+	}
+	if s := fmt.Sprint(seen); s != "[11 12 50 90]" {
+		t.Logf("Expected cursor to visit [11 12 50 90], saw %s", s)
+		t.Fail()
+	}
+
+	lt.Put(100, 100)
+	if n, ok := lc.Next(); !ok || n.Key() != 100 {
+		t.Logf("Expected exhausted cursor to pick up key 100 once inserted, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+
+	t.Run("max_key", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys([]uint{^uint(0)})
+		lc := lt.LiveCursor(0)
+		if n, ok := lc.Next(); !ok || n.Key() != ^uint(0) {
+			t.Logf("Expected the maximum key to be returned, saw %v (%t)", n, ok)
+			t.Fail()
+		}
+		if _, ok := lc.Next(); ok {
+			t.Log("Expected cursor not to wrap around after the maximum key")
+			t.Fail()
+		}
+	})
+
+	if _, ok := gerbst.NewLockingTree().LiveCursor(0).Next(); ok {
+		t.Log("Expected cursor over an empty tree to return nothing")
+		t.Fail()
+	}
+}
@@ -1,7 +1,43 @@
 package gerbst
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/disiqueira/gotree"
+)
+
+var (
+	// ErrCountOverflow is returned by PutChecked when inserting another node would overflow the tree's node count
+	ErrCountOverflow = errors.New("node count would overflow")
+	// ErrDepthOverflow is returned by PutChecked when inserting another node would overflow its depth
+	ErrDepthOverflow = errors.New("node depth would overflow")
+	// ErrEmptyTree is returned by operations that have no meaningful result for a tree without nodes
+	ErrEmptyTree = errors.New("tree is empty")
+	// ErrUnsortedKeys is returned by NewBalancedTreeWithSortedKeysChecked when its keys are not strictly ascending
+	ErrUnsortedKeys = errors.New("keys are not in strictly ascending order")
+	// ErrKeyNotFound is returned by operations that require a key to be present in the tree
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrMissingChild is returned by RotateLeft and RotateRight when the child to be promoted is absent
+	ErrMissingChild = errors.New("node is missing required child")
+	// ErrSlotOccupied is returned by PutAt when the requested position already holds a node
+	ErrSlotOccupied = errors.New("position is already occupied")
+	// ErrOrderViolation is returned by PutAt when the requested position would violate binary search tree ordering
+	ErrOrderViolation = errors.New("position would violate key ordering")
+	// ErrUnsupportedVersion is returned when decoding data written at a format version this package cannot read
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+	// ErrTreeFull is returned when inserting a new key would exceed the limit set by WithInsertLimit
+	ErrTreeFull = errors.New("tree is full")
+	// ErrPriorityShaped is returned by PutAt on a tree built WithRandomizedInsertPriority, whose shape is decided by
+	// the priorities of its keys
+	ErrPriorityShaped = errors.New("tree shape is decided by insert priority")
 )
 
 // LockingNodeSearchFunc is used in conjunction with LockingTree.SearchFunc to recurse through all nodes present in the
@@ -9,31 +45,130 @@ import (
 type LockingNodeSearchFunc = func(node *LockingTree) (continue_ bool)
 
 // LockingTree represents a singular position at any point within the tree.
+//
+// A nil *LockingTree behaves as an empty tree for every method that only reads from it.  Methods that write to the
+// tree, such as Put and Delete, panic when called on a nil *LockingTree.
 type LockingTree struct {
 	mu sync.RWMutex
 
 	root *treeNode
+
+	duplicates uint
+
+	maxCount       uint
+	evictionPolicy EvictionPolicy
+
+	// insertLimit, if set, is the number of nodes beyond which new keys are refused rather than evicting
+	insertLimit uint
+
+	depthBase         DepthBase
+	keyNormalizer     func(uint) uint
+	autoRebalance     float64
+	treap             bool
+	treapSeed         uint64
+	compactPrinting   bool
+	collisionResolver CollisionResolverFunc
+
+	// arena, if set, provides memory for new nodes
+	arena *nodeArena
+
+	// mods is incremented upon every insertion, deletion, or restructuring
+	mods uint64
+
+	// keys caches the result of Keys until the set of keys changes
+	keysMu sync.Mutex
+	keys   []uint
+
+	// negCache, if set, remembers keys recently looked up but not found
+	negCache *negativeCache
+
+	// valueIndex, if set, maps each value to the keys holding it
+	valueIndex valueIndex
+
+	// reentrancy enables the detection of calls made from within callbacks run under the write lock, and
+	// callbackOwner holds the id of the goroutine running such a callback, or 0
+	reentrancy    bool
+	callbackOwner int64
+
+	// comparisons, if set, tallies the key comparisons performed by lookups.  It is allocated separately to guarantee
+	// the 64-bit alignment required by atomic operations.
+	comparisons *uint64
 }
 
-// NewLockingTree constructs a new root node.  Value is optional, if left blank will be set to value of key.
-func NewLockingTree() *LockingTree {
+// NewLockingTree constructs a new, empty tree configured with the provided options.
+func NewLockingTree(opts ...LockingTreeOption) *LockingTree {
 	lt := new(LockingTree)
+	for _, opt := range opts {
+		opt(lt)
+	}
+	return lt
+}
+
+// derive constructs a new, empty tree configured with the same options as this tree, for use by methods that return a
+// tree built from this one.  The negative cache, value index, and comparison counter are allocated afresh rather than
+// shared.  The limit set by WithInsertLimit is not carried over, as the derived tree may already hold more nodes than
+// it allows.  A nil tree derives a tree with default options.
+func (n *LockingTree) derive() *LockingTree {
+	lt := NewLockingTree()
+	if n == nil {
+		return lt
+	}
+	lt.depthBase = n.depthBase
+	lt.keyNormalizer = n.keyNormalizer
+	lt.autoRebalance = n.autoRebalance
+	lt.treap = n.treap
+	lt.treapSeed = n.treapSeed
+	lt.compactPrinting = n.compactPrinting
+	lt.collisionResolver = n.collisionResolver
+	lt.reentrancy = n.reentrancy
+	if n.negCache != nil {
+		lt.negCache = newNegativeCache(cap(n.negCache.order))
+	}
+	if n.valueIndex != nil {
+		lt.valueIndex = make(valueIndex)
+	}
+	if n.comparisons != nil {
+		lt.comparisons = new(uint64)
+	}
 	return lt
 }
 
+// plant places root, whose metadata need not yet be valid, at the top of this tree, seating it at this tree's depth
+// base and indexing its values.  Caller must hold the write lock, or be the only holder of this tree.
+func (n *LockingTree) plant(root *treeNode) {
+	n.root = root
+	n.root.rebuildMeta(nil, n.depthBase.rootDepth(), NodeSideRoot)
+	if n.valueIndex != nil {
+		n.valueIndex.reindex(n.root)
+	}
+}
+
 // NewLockingTreeWithKeys populates the tree using a list of keys.  The value of each node will be that of the key of
 // that node.
-func NewLockingTreeWithKeys(keys []uint) *LockingTree {
-	lt := NewLockingTree()
+func NewLockingTreeWithKeys(keys []uint, opts ...LockingTreeOption) *LockingTree {
+	lt := NewLockingTree(opts...)
 	for _, k := range keys {
 		lt.Put(k, k)
 	}
 	return lt
 }
 
+// NewLockingTreeWithKeysValue populates the tree using a list of keys, setting the value of every node to the provided
+// value.
+func NewLockingTreeWithKeysValue(keys []uint, value interface{}, opts ...LockingTreeOption) *LockingTree {
+	lt := NewLockingTree(opts...)
+	for _, k := range keys {
+		lt.Put(k, value)
+	}
+	return lt
+}
+
 // Count returns the total number of nodes within this tree
 func (n *LockingTree) Count() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -43,7 +178,10 @@ func (n *LockingTree) Count() uint {
 
 // CountLeft returns the total number of nodes on the left side of this tree
 func (n *LockingTree) CountLeft() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -53,7 +191,10 @@ func (n *LockingTree) CountLeft() uint {
 
 // CountRight returns the total number of nodes on the right side of this tree
 func (n *LockingTree) CountRight() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -63,7 +204,10 @@ func (n *LockingTree) CountRight() uint {
 
 // LowestKey returns the smallest key in this node's subtree
 func (n *LockingTree) LowestKey() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -73,7 +217,10 @@ func (n *LockingTree) LowestKey() uint {
 
 // HighestKey returns the highest key in this node's subtree
 func (n *LockingTree) HighestKey() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -81,9 +228,13 @@ func (n *LockingTree) HighestKey() uint {
 	return n.root.hiKey
 }
 
-// DepthMax returns the absolute deepest a branch goes
+// DepthMax returns the depth of the deepest node.  By default the root is at depth 1, see WithDepthBase.  Returns 0 if
+// this tree is empty.
 func (n *LockingTree) DepthMax() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -91,9 +242,12 @@ func (n *LockingTree) DepthMax() uint {
 	return n.root.depthMax
 }
 
-// DepthMaxLeft returns the maximum depth of the left branch
+// DepthMaxLeft returns the depth of the deepest node in the left branch, or 0 if there is no left branch
 func (n *LockingTree) DepthMaxLeft() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -101,9 +255,12 @@ func (n *LockingTree) DepthMaxLeft() uint {
 	return n.root.depthMaxLeft
 }
 
-// DepthMaxRight returns the maximum depth of the right branch
+// DepthMaxRight returns the depth of the deepest node in the right branch, or 0 if there is no right branch
 func (n *LockingTree) DepthMaxRight() uint {
-	n.mu.RLock()
+	if n == nil {
+		return 0
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return 0
@@ -111,61 +268,1738 @@ func (n *LockingTree) DepthMaxRight() uint {
 	return n.root.depthMaxRight
 }
 
+// Depth returns the depth of the node with the provided key, or false if the key is absent.  By default the root is at
+// depth 1, see WithDepthBase.
+func (n *LockingTree) Depth(key uint) (uint, bool) {
+	key = n.normalizeKey(key)
+	if n == nil {
+		return 0, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || key < n.root.loKey || key > n.root.hiKey {
+		return 0, false
+	}
+	if tn := n.root.find(key); tn != nil {
+		return tn.depth, true
+	}
+	return 0, false
+}
+
+// Height returns the number of nodes on the longest path from the root to a leaf, or 0 if this tree is empty.  Unlike
+// the depth accessors it does not depend on the depth base.
+func (n *LockingTree) Height() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	return n.root.depthMax - n.root.depth + 1
+}
+
+// InBounds returns true if key lies within [LowestKey, HighestKey].  A key outside these bounds is certainly absent,
+// though one within them may be absent too.  Always returns false for an empty tree.
+func (n *LockingTree) InBounds(key uint) bool {
+	if n == nil {
+		return false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	return n.root != nil && key >= n.root.loKey && key <= n.root.hiKey
+}
+
 // Get attempts to retrieve a node by value
 func (n *LockingTree) Get(key uint) (*Node, bool) {
-	n.mu.RLock()
+	key = n.normalizeKey(key)
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	// fast fail if this tree is empty or if the requested key is beyond our bounds
 	if n.root == nil || key < n.root.loKey || key > n.root.hiKey {
 		return nil, false
 	}
-	return n.root.Get(key)
+	if n.negCache == nil {
+		n.countComparisons(key)
+		return n.root.Get(key)
+	}
+	if n.negCache.has(key) {
+		return nil, false
+	}
+	n.countComparisons(key)
+	node, ok := n.root.Get(key)
+	if !ok {
+		n.negCache.add(key)
+	}
+	return node, ok
 }
 
 // GetRecurse attempts to retrieve a node by key using recursion
 func (n *LockingTree) GetRecurse(key uint) (*Node, bool) {
-	n.mu.RLock()
+	key = n.normalizeKey(key)
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	// fast fail if this tree is empty or if the requested key is beyond our bounds
 	if n.root == nil || key < n.root.loKey || key > n.root.hiKey {
 		return nil, false
 	}
+	n.countComparisons(key)
 	return n.root.GetRecurse(key)
 }
 
-// Put inserts a new node or updates the value of an existing node
+// Put inserts a new node or updates the value of an existing node.  A new key is silently refused if this tree is at
+// the limit set by WithInsertLimit, see PutErr.
 func (n *LockingTree) Put(key uint, value interface{}) {
-	n.mu.Lock()
+	key = n.normalizeKey(key)
+	n.lock()
 	defer n.mu.Unlock()
-	n.put(key, value, false)
+	n.putCounted(key, value, false)
 }
 
 // PutRecurse inserts a new node or updates the value of an existing node using recursion
 func (n *LockingTree) PutRecurse(key uint, value interface{}) {
-	n.mu.Lock()
+	key = n.normalizeKey(key)
+	n.lock()
 	defer n.mu.Unlock()
-	n.put(key, value, true)
+	n.putCounted(key, value, true)
 }
 
-func (n *LockingTree) put(key uint, value interface{}, recurse bool) {
-	if n.root == nil {
-		n.root = newTreeNode(key, value, 1, NodeSideRoot, nil, nil, nil)
-		return
+// putCounted behaves as put, additionally counting the put toward DuplicateCount if key was already present.  Caller
+// must hold the write lock.
+func (n *LockingTree) putCounted(key uint, value interface{}, recurse bool) {
+	// put refuses a new key only when this tree is full, in which case the key remains absent
+	if !n.put(key, value, recurse) && !n.full(key) {
+		n.duplicates++
+	}
+}
+
+// PutErr behaves as Put, but returns ErrTreeFull, leaving the tree untouched, if key is absent and this tree is at the
+// limit set by WithInsertLimit.  Updates to existing keys always succeed.
+func (n *LockingTree) PutErr(key uint, value interface{}) error {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	if n.full(key) {
+		return fmt.Errorf("unable to insert key %d: %w", key, ErrTreeFull)
+	}
+	n.put(key, value, false)
+	return nil
+}
+
+// full returns true if key is absent and this tree already holds the number of nodes set by WithInsertLimit.  Caller must
+// hold at least the read lock.
+func (n *LockingTree) full(key uint) bool {
+	return n.insertLimit > 0 && n.root != nil && n.root.count >= n.insertLimit && n.root.find(key) == nil
+}
+
+// put expects the caller to hold the write lock, and returns true if a new node was created.  A new key is refused,
+// returning false, if this tree is full.
+func (n *LockingTree) put(key uint, value interface{}, recurse bool) bool {
+	if n.full(key) {
+		return false
 	}
-	if recurse {
-		n.root.PutRecurse(key, value)
+	var (
+		inserted bool
+		existing *treeNode
+	)
+	if (n.collisionResolver != nil || n.valueIndex != nil) && n.root != nil {
+		existing = n.root.find(key)
+	}
+	n.countComparisons(key)
+	// let the resolver decide the surviving value of an existing key
+	if n.collisionResolver != nil && existing != nil {
+		n.inCallback(func() { value = n.collisionResolver(existing.value, value) })
+	}
+	if n.valueIndex != nil {
+		if existing != nil {
+			n.valueIndex.remove(existing.value, key)
+		}
+		n.valueIndex.add(value, key)
+	}
+	// make room for a new key if we're at capacity
+	if n.maxCount > 0 && n.root != nil && n.root.count >= n.maxCount && n.root.find(key) == nil {
+		n.evict()
+	}
+	if n.arena != nil {
+		inserted = n.putArena(key, value)
+	} else if n.root == nil {
+		n.root = newTreeNode(key, value, n.depthBase.rootDepth(), NodeSideRoot, nil, nil, nil)
+		inserted = true
+	} else if recurse {
+		inserted = n.root.PutRecurse(key, value)
 	} else {
-		n.root.Put(key, value)
+		inserted = n.root.Put(key, value)
+	}
+	if inserted {
+		n.changed()
+		if n.negCache != nil {
+			n.negCache.remove(key)
+		}
+		if n.treap {
+			n.treapSiftUp(n.root.find(key))
+		}
+		if n.autoRebalance > 0 && n.root.count > 2 &&
+			float64(n.root.depthMax-n.root.depth+1) > n.autoRebalance*math.Log2(float64(n.root.count)) {
+			n.rebalance()
+		}
+	}
+	return inserted
+}
+
+// normalizeKey passes key through the normalizer set by WithKeyNormalizer, if any.  The normalizer is fixed at
+// construction, so no lock is required.
+func (n *LockingTree) normalizeKey(key uint) uint {
+	if n == nil || n.keyNormalizer == nil {
+		return key
 	}
+	return n.keyNormalizer(key)
+}
+
+// normalizeKeys returns a copy of keys with each passed through the normalizer set by WithKeyNormalizer, or keys itself
+// if there is none
+func (n *LockingTree) normalizeKeys(keys []uint) []uint {
+	if n == nil || n.keyNormalizer == nil {
+		return keys
+	}
+	normalized := make([]uint, len(keys))
+	for i, k := range keys {
+		normalized[i] = n.keyNormalizer(k)
+	}
+	return normalized
+}
+
+// countComparisons adds the number of keys a lookup of the provided key compares against to the tally enabled by
+// WithComparisonCounter, doing nothing if it is disabled.  Caller must hold at least the read lock.
+func (n *LockingTree) countComparisons(key uint) {
+	if n.comparisons == nil || n.root == nil {
+		return
+	}
+	tn, _ := n.root.attachPoint(key)
+	atomic.AddUint64(n.comparisons, uint64(tn.depth-n.root.depth+1))
+}
+
+// ComparisonCount returns the number of key comparisons performed by Get, GetRecurse, and every form of Put since this
+// tree was constructed or since the last call to ResetComparisonCount.  Always returns 0 unless this tree was
+// constructed WithComparisonCounter.
+func (n *LockingTree) ComparisonCount() uint {
+	if n == nil || n.comparisons == nil {
+		return 0
+	}
+	return uint(atomic.LoadUint64(n.comparisons))
+}
+
+// ResetComparisonCount resets the count returned by ComparisonCount to zero
+func (n *LockingTree) ResetComparisonCount() {
+	if n == nil || n.comparisons == nil {
+		return
+	}
+	atomic.StoreUint64(n.comparisons, 0)
+}
+
+// changed must be called, with the write lock held, whenever the set of keys within this tree changes
+func (n *LockingTree) changed() {
+	n.mods++
+	n.keys = nil
+}
+
+// DuplicateCount returns the number of times Put or PutRecurse has been called with a key already present in this
+// tree since it was constructed or since the last call to ResetDuplicateCount
+func (n *LockingTree) DuplicateCount() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	return n.duplicates
+}
+
+// ResetDuplicateCount zeroes the duplicate counter, returning the value it held prior to being reset
+func (n *LockingTree) ResetDuplicateCount() uint {
+	n.lock()
+	defer n.mu.Unlock()
+	prev := n.duplicates
+	n.duplicates = 0
+	return prev
+}
+
+// nodeLabel returns the text used to represent a node when printing this tree
+func (n *LockingTree) nodeLabel(tn *treeNode) string {
+	if n.compactPrinting {
+		return tn.compactString()
+	}
+	return tn.String()
 }
 
 // StringTree returns a string representation of the tree meant for printing
 func (n *LockingTree) StringTree() string {
-	n.mu.RLock()
+	if n == nil {
+		return ""
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return ""
+	}
+	return printTree(n.root, n.nodeLabel, unicodeGlyphs)
+}
+
+// IsAncestor returns true if the node with key ancestor lies on the path from the root to the node with key descendant.
+// A node is not considered its own ancestor.  Returns ErrKeyNotFound if either key is absent.
+func (n *LockingTree) IsAncestor(ancestor, descendant uint) (bool, error) {
+	ancestor, descendant = n.normalizeKey(ancestor), n.normalizeKey(descendant)
+	if n == nil {
+		return false, fmt.Errorf("key %d: %w", ancestor, ErrKeyNotFound)
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return false, fmt.Errorf("key %d: %w", ancestor, ErrKeyNotFound)
+	}
+	path, ok := n.root.path(descendant)
+	if !ok {
+		return false, fmt.Errorf("key %d: %w", descendant, ErrKeyNotFound)
+	}
+	for _, tn := range path[:len(path)-1] {
+		if tn.key == ancestor {
+			return true, nil
+		}
+	}
+	if ancestor != descendant && n.root.find(ancestor) == nil {
+		return false, fmt.Errorf("key %d: %w", ancestor, ErrKeyNotFound)
+	}
+	return false, nil
+}
+
+// DivergencePoint returns the last node shared by the search paths for keys a and b, which is where the paths split,
+// or where both end if neither splits before reaching a missing branch.  Neither key need be present, so this also
+// locates where Put would place absent keys relative to each other.  If either key is present and lies on the other's
+// path, its node is returned.  Returns false only if this tree is empty.
+func (n *LockingTree) DivergencePoint(a, b uint) (*Node, bool) {
+	a, b = n.normalizeKey(a), n.normalizeKey(b)
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	tn := n.root
+	for {
+		var next *treeNode
+		if a < tn.key && b < tn.key {
+			next = tn.left
+		} else if a > tn.key && b > tn.key {
+			next = tn.right
+		}
+		if next == nil {
+			return tn.Node, true
+		}
+		tn = next
+	}
+}
+
+// StringTreeWithValueFunc behaves as StringTree, formatting each node's value with vf rather than %v.  Every node is
+// printed in the format of SIDE[KEY(VALUE)], regardless of WithCompactPrinting.  A nil vf is equivalent to StringTree.
+func (n *LockingTree) StringTreeWithValueFunc(vf func(interface{}) string) string {
+	if vf == nil {
+		return n.StringTree()
+	}
+	if n == nil {
+		return ""
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return ""
+	}
+	return printTree(n.root, func(tn *treeNode) string {
+		return fmt.Sprintf("%s[%d(%s)]", tn.side, tn.key, vf(tn.value))
+	}, unicodeGlyphs)
+}
+
+// PathCost returns the sum of the keys of every node from root down to and including the node with the provided key,
+// or false if the key is absent
+func (n *LockingTree) PathCost(key uint) (uint, bool) {
+	key = n.normalizeKey(key)
+	if n == nil {
+		return 0, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || key < n.root.loKey || key > n.root.hiKey {
+		return 0, false
+	}
+	path, ok := n.root.path(key)
+	if !ok {
+		return 0, false
+	}
+	var cost uint
+	for _, tn := range path {
+		cost += tn.key
+	}
+	return cost, true
+}
+
+// Contains returns true if a node with the provided key exists within this tree
+func (n *LockingTree) Contains(key uint) bool {
+	if n == nil {
+		return false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	return n.contains(key)
+}
+
+// ContainsAll returns true only if every one of the provided keys exists within this tree.  The read lock is held for
+// the entire batch, and the check halts at the first absent key.
+func (n *LockingTree) ContainsAll(keys []uint) bool {
+	if n == nil {
+		return len(keys) == 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	for _, k := range keys {
+		if !n.contains(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsAny returns true if at least one of the provided keys exists within this tree.  The read lock is held for
+// the entire batch, and the check halts at the first present key.
+func (n *LockingTree) ContainsAny(keys []uint) bool {
+	if n == nil {
+		return false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	for _, k := range keys {
+		if n.contains(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains expects the caller to hold at least the read lock
+func (n *LockingTree) contains(key uint) bool {
+	key = n.normalizeKey(key)
+	if n.root == nil || key < n.root.loKey || key > n.root.hiKey {
+		return false
+	}
+	_, ok := n.root.Get(key)
+	return ok
+}
+
+// GetMany returns the values of each of the provided keys that exists within this tree, keyed as requested, omitting
+// any that are absent.  The read lock is held for the entire batch.
+func (n *LockingTree) GetMany(keys []uint) map[uint]interface{} {
+	values := make(map[uint]interface{}, len(keys))
+	if n == nil {
+		return values
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return values
+	}
+	for _, k := range keys {
+		key := n.normalizeKey(k)
+		if key < n.root.loKey || key > n.root.hiKey {
+			continue
+		}
+		if tn := n.root.find(key); tn != nil {
+			values[k] = tn.value
+		}
+	}
+	return values
+}
+
+// WouldAdd returns the number of new nodes that putting each of the provided keys would create, which is the number of
+// distinct keys absent from this tree, without modifying it.  The read lock is held for the entire batch.
+func (n *LockingTree) WouldAdd(keys []uint) uint {
+	if n != nil {
+		n.rlock()
+		defer n.mu.RUnlock()
+	}
+	seen := make(map[uint]struct{}, len(keys))
+	for _, k := range keys {
+		key := n.normalizeKey(k)
+		if _, ok := seen[key]; ok || (n != nil && n.contains(k)) {
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+	return uint(len(seen))
+}
+
+// MapValues replaces the value of every node in this tree with the value returned by fn, leaving keys and structure
+// untouched.  fn is called in ascending key order while the write lock is held.
+func (n *LockingTree) MapValues(fn func(key uint, old interface{}) interface{}) {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return
+	}
+	n.inCallback(func() {
+		n.root.walkInOrder(func(tn *treeNode) bool {
+			tn.setValue(fn(tn.key, tn.value))
+			return true
+		})
+	})
+	if n.valueIndex != nil {
+		n.valueIndex.reindex(n.root)
+	}
+}
+
+// InternValues replaces the value of every node with the first value, in ascending key order, that equal reports as
+// equal to it, so that nodes holding equal values share a single instance and the duplicates may be reclaimed.  This
+// only helps, and is only safe, when values are immutable: a later change to a shared value is seen by every node
+// holding it.  equal is called while the write lock is held, and as each value is compared with every distinct value
+// before it this is O(n * distinct values).
+func (n *LockingTree) InternValues(equal func(a, b interface{}) bool) {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return
+	}
+	var canon []interface{}
+	n.inCallback(func() {
+		n.root.walkInOrder(func(tn *treeNode) bool {
+			for _, c := range canon {
+				if equal(c, tn.value) {
+					tn.setValue(c)
+					return true
+				}
+			}
+			canon = append(canon, tn.value)
+			return true
+		})
+	})
+	if n.valueIndex != nil {
+		n.valueIndex.reindex(n.root)
+	}
+}
+
+// Fold performs an in-order left fold over this tree, passing the accumulator returned by each call of fn into the
+// next and returning the final accumulator.  acc is returned as-is if the tree is empty.
+func (n *LockingTree) Fold(acc interface{}, fn func(acc interface{}, key uint, value interface{}) interface{}) interface{} {
+	if n == nil {
+		return acc
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return acc
+	}
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		acc = fn(acc, tn.key, tn.value)
+		return true
+	})
+	return acc
+}
+
+// Diameter returns the number of edges on the longest path between any two nodes in this tree, which need not pass
+// through the root
+func (n *LockingTree) Diameter() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	_, d := n.root.diameter()
+	return d
+}
+
+// ApproxSizeBytes returns a rough estimate of the memory retained by this tree: the size of the tree itself plus the
+// size of one internal node and its exported Node per entry.  Values are opaque to the tree and so are NOT included,
+// nor is any allocator overhead, so treat the result as a lower bound.
+func (n *LockingTree) ApproxSizeBytes() uintptr {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	size := unsafe.Sizeof(*n)
+	if n.root != nil {
+		size += uintptr(n.root.count) * (unsafe.Sizeof(treeNode{}) + unsafe.Sizeof(Node{}))
+	}
+	return size
+}
+
+// StringTreeASCII returns the same representation as StringTree, drawn with plain ASCII characters rather than
+// Unicode box-drawing characters.  Useful for logs and CI output that mangle the latter.
+func (n *LockingTree) StringTreeASCII() string {
+	if n == nil {
+		return ""
+	}
+	n.rlock()
 	defer n.mu.RUnlock()
 	if n.root == nil {
 		return ""
 	}
-	tree := n.root.buildTreePrinter()
-	return tree.Print()
+	return printTree(n.root, n.nodeLabel, asciiGlyphs)
+}
+
+// Rekey returns a new tree containing every node of this tree with its key passed through fn and its value preserved.
+// fn must be strictly increasing across this tree's keys: an error is returned if two keys map to the same new key or
+// if the relative order of any keys would change.  As ordering is preserved, the new tree has the exact same shape, and
+// is configured with the same options as this tree.  With WithKeyNormalizer, each new key is normalized, and fn must be
+// strictly increasing once its results are normalized.
+func (n *LockingTree) Rekey(fn func(old uint) uint) (*LockingTree, error) {
+	if n == nil {
+		return NewLockingTree(), nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+
+	lt := n.derive()
+	if n.root == nil {
+		return lt, nil
+	}
+
+	rekey := func(old uint) uint { return lt.normalizeKey(fn(old)) }
+	var (
+		err     error
+		prev    *treeNode
+		prevKey uint
+	)
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		key := rekey(tn.key)
+		if prev != nil {
+			if key == prevKey {
+				err = fmt.Errorf("keys %d and %d both map to %d", prev.key, tn.key, key)
+			} else if key < prevKey {
+				err = fmt.Errorf("key %d maps to %d which is lower than %d mapped from %d", tn.key, key, prevKey, prev.key)
+			}
+		}
+		prev = tn
+		prevKey = key
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lt.plant(n.root.clone(nil, rekey))
+	return lt, nil
+}
+
+// TreePrinter returns a freshly constructed gotree.Tree mirroring this tree, for callers that wish to annotate or render
+// it themselves.  Returns nil if this tree is empty.
+func (n *LockingTree) TreePrinter() gotree.Tree {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+	return n.root.buildTreePrinter(n.nodeLabel)
+}
+
+// Delete removes the node with the provided key, returning it or false if the key was absent
+func (n *LockingTree) Delete(key uint) (*Node, bool) {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil || key < n.root.loKey || key > n.root.hiKey {
+		return nil, false
+	}
+	tn := n.root.find(key)
+	if tn == nil {
+		return nil, false
+	}
+	n.unlink(tn)
+	return tn.Node, true
+}
+
+// Rebalance rebuilds this tree into a height-balanced shape holding the same keys and values.  This is O(n).
+func (n *LockingTree) Rebalance() {
+	n.lock()
+	defer n.mu.Unlock()
+	n.rebalance()
+}
+
+// BalanceDSW balances this tree in place using the Day-Stout-Warren algorithm, flattening it into a sorted vine through
+// right rotations before compressing the vine into a balanced tree through left rotations.  No nodes are allocated,
+// and each keeps its ID.  Unlike Rebalance, a tree that is already balanced, as reported by IsBalanced, is left as it
+// is, so this performs exactly the number of rotations reported by RotationsToBalance.  This is O(n).
+func (n *LockingTree) BalanceDSW() {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil || n.root.heightBalanced() {
+		return
+	}
+	n.root, _ = balanceDSW(n.root)
+	n.root.rebuildMeta(nil, n.depthBase.rootDepth(), NodeSideRoot)
+	// the set of keys is unchanged, but any iterator may now be positioned incorrectly
+	n.mods++
+}
+
+// rebalance rebuilds this tree into a height-balanced shape, relinking its existing nodes so that each keeps its ID.
+// Caller must hold the write lock.
+func (n *LockingTree) rebalance() {
+	if n.root == nil {
+		return
+	}
+	tns := make([]*treeNode, 0, n.root.count)
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		tns = append(tns, tn)
+		return true
+	})
+	n.root = linkBalanced(tns)
+	n.root.rebuildMeta(nil, n.depthBase.rootDepth(), NodeSideRoot)
+	// the set of keys is unchanged, but any iterator may now be positioned incorrectly
+	n.mods++
+}
+
+// linkBalanced relinks the provided nodes, which must be in ascending key order, into a height-balanced tree and
+// returns its root, or nil if there are none.  Only child links are set, so the caller must rebuild metadata.
+func linkBalanced(tns []*treeNode) *treeNode {
+	if len(tns) == 0 {
+		return nil
+	}
+	mid := len(tns) / 2
+	tn := tns[mid]
+	tn.left = linkBalanced(tns[:mid])
+	tn.right = linkBalanced(tns[mid+1:])
+	return tn
+}
+
+// GetByID returns the node currently holding the ID returned by Node.ID, or false if no node in this tree holds it.
+// As IDs are not indexed this visits every node, and is O(n).
+func (n *LockingTree) GetByID(id uint64) (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	var found *Node
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		if tn.id == id {
+			found = tn.Node
+			return false
+		}
+		return true
+	})
+	return found, found != nil
+}
+
+// RotateLeft performs a left rotation around the node with the provided key, promoting its right child into its place
+// and making it that child's left child.  Returns ErrKeyNotFound if the key is absent, or ErrMissingChild if the node
+// has no right child.  Every node of the rotated subtree has its depth updated, so this is O(size of the subtree).
+func (n *LockingTree) RotateLeft(key uint) error {
+	return n.rotate(key, true)
+}
+
+// RotateRight performs a right rotation around the node with the provided key, promoting its left child into its place
+// and making it that child's right child.  Returns ErrKeyNotFound if the key is absent, or ErrMissingChild if the node
+// has no left child.  Every node of the rotated subtree has its depth updated, so this is O(size of the subtree).
+func (n *LockingTree) RotateRight(key uint) error {
+	return n.rotate(key, false)
+}
+
+func (n *LockingTree) rotate(key uint, left bool) error {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	var tn *treeNode
+	if n.root != nil {
+		tn = n.root.find(key)
+	}
+	if tn == nil {
+		return fmt.Errorf("key %d: %w", key, ErrKeyNotFound)
+	}
+	if left && tn.right == nil {
+		return fmt.Errorf("unable to rotate key %d left: %w", key, ErrMissingChild)
+	} else if !left && tn.left == nil {
+		return fmt.Errorf("unable to rotate key %d right: %w", key, ErrMissingChild)
+	}
+	rotate(&n.root, tn, left)
+	// the set of keys is unchanged, but any iterator may now be positioned incorrectly
+	n.mods++
+	return nil
+}
+
+// Range returns every node whose key lies within [lo, hi] in ascending key order, skipping branches that lie wholly
+// outside the range.  Returns nil if lo is greater than hi.
+func (n *LockingTree) Range(lo, hi uint) []*Node {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || lo > hi {
+		return nil
+	}
+	var nodes []*Node
+	n.root.walkRange(lo, hi, func(tn *treeNode) bool {
+		nodes = append(nodes, tn.Node)
+		return true
+	})
+	return nodes
+}
+
+// RemoveRange removes every node whose key lies within [lo, hi], returning the removed nodes in ascending key order as
+// Range would have returned them.  Returns nil if lo is greater than hi.
+func (n *LockingTree) RemoveRange(lo, hi uint) []*Node {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil || lo > hi {
+		return nil
+	}
+	var tns []*treeNode
+	n.root.walkRange(lo, hi, func(tn *treeNode) bool {
+		tns = append(tns, tn)
+		return true
+	})
+	// capture every node before any removal re-seats the others
+	nodes := make([]*Node, len(tns))
+	for i, tn := range tns {
+		nodes[i] = tn.Node
+	}
+	for _, tn := range tns {
+		n.unlink(tn)
+	}
+	return nodes
+}
+
+// DeleteFunc removes every node for which pred returns true, returning the number of nodes removed.  pred is called once
+// for each node in ascending key order before any is removed, and is called while the write lock is held, so it must
+// not call any method of this tree.
+//
+// Removal is done in a single O(n) pass: if any node is removed, the surviving nodes are relinked into a
+// height-balanced shape, as with Rebalance, each keeping its ID.  If no node is removed the tree is left untouched.
+func (n *LockingTree) DeleteFunc(pred func(key uint, value interface{}) bool) uint {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return 0
+	}
+	var removed, kept []*treeNode
+	n.inCallback(func() {
+		n.root.walkInOrder(func(tn *treeNode) bool {
+			if pred(tn.key, tn.value) {
+				removed = append(removed, tn)
+			} else {
+				kept = append(kept, tn)
+			}
+			return true
+		})
+	})
+	if len(removed) == 0 {
+		return 0
+	}
+
+	for _, tn := range removed {
+		tn.parent, tn.left, tn.right = nil, nil, nil
+		if n.valueIndex != nil {
+			n.valueIndex.remove(tn.value, tn.key)
+		}
+	}
+	n.root = linkBalanced(kept)
+	if n.root != nil {
+		n.root.rebuildMeta(nil, n.depthBase.rootDepth(), NodeSideRoot)
+	}
+	n.changed()
+	return uint(len(removed))
+}
+
+// Drain removes every node from this tree, returning their keys and values in ascending key order.  The tree is empty
+// once Drain returns.
+func (n *LockingTree) Drain() []Entry {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return nil
+	}
+	pairs := n.root.entries()
+	n.root = nil
+	n.changed()
+	if n.valueIndex != nil {
+		n.valueIndex.reindex(nil)
+	}
+	return pairs
+}
+
+// Destroy removes every node from this tree, severing the parent and child links of each in post-order so that no
+// removed node references another.  Nodes previously returned by this tree remain valid.  The tree is empty, and
+// usable, once Destroy returns.
+func (n *LockingTree) Destroy() {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return
+	}
+	// climb back up through parent links rather than keeping a stack, detaching each node once its branches are gone
+	tn := n.root
+	for tn != nil {
+		if tn.left != nil {
+			tn = tn.left
+		} else if tn.right != nil {
+			tn = tn.right
+		} else {
+			parent := tn.parent
+			if parent != nil {
+				if parent.left == tn {
+					parent.left = nil
+				} else {
+					parent.right = nil
+				}
+			}
+			tn.parent = nil
+			tn = parent
+		}
+	}
+	n.root = nil
+	n.changed()
+	if n.valueIndex != nil {
+		n.valueIndex.reindex(nil)
+	}
+}
+
+// DeleteMin removes the node with the lowest key, returning it or false if this tree is empty.  The node is found by
+// following left branches alone, and as it has no left branch of its own it is replaced by its right branch directly.
+// Finding it is O(height), but as every node records its own depth, each node of that right branch must then be
+// re-seated one level higher, so removal is O(height + size of the right branch).  In a height-balanced tree, such as
+// one just rebuilt by Rebalance, the right branch holds at most one node and removal is O(height).
+func (n *LockingTree) DeleteMin() (*Node, bool) {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return nil, false
+	}
+	tn := n.root.lowest()
+	n.unlink(tn)
+	return tn.Node, true
+}
+
+// DeleteMax removes the node with the highest key, returning it or false if this tree is empty.  The node is found by
+// following right branches alone, and as it has no right branch of its own it is replaced by its left branch directly.
+// As with DeleteMin, removal is O(height + size of the left branch), which is O(height) in a height-balanced tree.
+func (n *LockingTree) DeleteMax() (*Node, bool) {
+	n.lock()
+	defer n.mu.Unlock()
+	if n.root == nil {
+		return nil, false
+	}
+	tn := n.root.highest()
+	n.unlink(tn)
+	return tn.Node, true
+}
+
+// SetMaxCount caps the number of nodes this tree may hold.  Once the cap is reached, each Put of a new key first
+// evicts a node chosen by the eviction policy, while updates to existing keys are always allowed.  If the tree already
+// holds more than max nodes, nodes are evicted until it does not.  A max of 0 removes the cap.
+func (n *LockingTree) SetMaxCount(max uint) {
+	n.lock()
+	defer n.mu.Unlock()
+	n.maxCount = max
+	if max == 0 {
+		return
+	}
+	for n.root != nil && n.root.count > max {
+		n.evict()
+	}
+}
+
+// MaxCount returns the current node cap, or 0 if there is none
+func (n *LockingTree) MaxCount() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	return n.maxCount
+}
+
+// InsertLimit returns the limit set by WithInsertLimit, beyond which new keys are refused, or 0 if there is none
+func (n *LockingTree) InsertLimit() uint {
+	if n == nil {
+		return 0
+	}
+	return n.insertLimit
+}
+
+// SetEvictionPolicy sets the policy used to choose which node is evicted once the cap set by SetMaxCount is reached.
+// Defaults to EvictSmallestKey.
+func (n *LockingTree) SetEvictionPolicy(policy EvictionPolicy) {
+	n.lock()
+	defer n.mu.Unlock()
+	n.evictionPolicy = policy
+}
+
+// evict expects the caller to hold the write lock and the tree to be non-empty
+func (n *LockingTree) evict() {
+	var tn *treeNode
+	switch n.evictionPolicy {
+	case EvictDeepest:
+		tn = n.root.deepest()
+	default:
+		tn = n.root.lowest()
+	}
+	n.unlink(tn)
+}
+
+// unlink removes tn from this tree.  Caller must hold the write lock.
+func (n *LockingTree) unlink(tn *treeNode) {
+	if n.treap {
+		// sifting down re-seats tn, so restore the node as it was while still part of the tree
+		node := tn.Node
+		n.treapSiftDown(tn)
+		defer func() { tn.Node = node }()
+	}
+	deleteNode(&n.root, tn)
+	n.changed()
+	if n.valueIndex != nil {
+		n.valueIndex.remove(tn.value, tn.key)
+	}
+}
+
+// selfCheckSamples is the maximum number of keys SelfCheck runs through Get and GetRecurse
+const selfCheckSamples = 64
+
+// SelfCheck performs a runtime consistency check of this tree, returning a descriptive error upon the first problem
+// found.  It validates the metadata and ordering of every node, then runs an evenly spaced sample of present keys,
+// along with their absent neighbours, through both Get and GetRecurse to verify the two walks agree.
+//
+// This is O(n) and holds the read lock throughout, so it is intended for staging and debugging rather than hot paths.
+func (n *LockingTree) SelfCheck() error {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+
+	if err := n.root.checkMeta(nil, n.root.depth, NodeSideRoot); err != nil {
+		return fmt.Errorf("metadata check failed: %w", err)
+	}
+	if err := n.root.validateOrder(); err != nil {
+		return fmt.Errorf("ordering check failed: %w", err)
+	}
+
+	return n.checkLookups(n.root.Get, n.root.GetRecurse)
+}
+
+// errLookupMismatch is wrapped by the error SelfCheck returns when the iterative and recursive lookups disagree
+var errLookupMismatch = errors.New("get and recursive get disagree")
+
+// checkLookups runs an evenly spaced sample of present keys, along with their absent neighbours, through get and
+// getRecurse, returning an error if they disagree or if get fails to locate a present key.  Caller must hold at least
+// the read lock, and the tree must not be empty.
+func (n *LockingTree) checkLookups(get, getRecurse func(uint) (*Node, bool)) error {
+	step := n.root.count/selfCheckSamples + 1
+	var (
+		i   uint
+		err error
+	)
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		if i%step == 0 {
+			for _, key := range []uint{tn.key - 1, tn.key, tn.key + 1} {
+				gn, gok := get(key)
+				rn, rok := getRecurse(key)
+				if gn != rn || gok != rok {
+					err = fmt.Errorf("%w on key %d: %v (%t) vs %v (%t)", errLookupMismatch, key, gn, gok, rn, rok)
+					return false
+				}
+				if key == tn.key && gn != tn.Node {
+					err = fmt.Errorf("get did not locate key %d", key)
+					return false
+				}
+			}
+		}
+		i++
+		return true
+	})
+	return err
+}
+
+// NewLockingTreeFromMap populates the tree using the keys and values of a map.  The keys are sorted, and then put in
+// the order that places the median of each range before the keys on either side of it, so the result is
+// deterministic and height-balanced regardless of map iteration order.  Simply putting sorted keys in ascending order
+// would instead produce a tree as deep as it is large.
+func NewLockingTreeFromMap(m map[uint]interface{}, opts ...LockingTreeOption) *LockingTree {
+	lt := NewLockingTree(opts...)
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	// put breadth-first by range so that each subtree's median is put before either of its halves
+	ranges := [][2]int{{0, len(keys)}}
+	for len(ranges) > 0 {
+		lo, hi := ranges[0][0], ranges[0][1]
+		ranges = ranges[1:]
+		if lo >= hi {
+			continue
+		}
+		mid := lo + (hi-lo)/2
+		lt.Put(keys[mid], m[keys[mid]])
+		ranges = append(ranges, [2]int{lo, mid}, [2]int{mid + 1, hi})
+	}
+	return lt
+}
+
+// NewLockingTreeConcurrent populates a tree using a list of keys, building it with up to the provided number of
+// goroutines.  The value of each node will be that of the key of that node.
+//
+// The key space is split into workers ranges by evenly spaced pivot keys.  Each range is built into its own subtree
+// concurrently, inserting keys in the order they were provided, then the subtrees are stitched beneath a balanced
+// tree of the pivots.  The result contains the same set of keys as NewLockingTreeWithKeys but, unlike it, not
+// necessarily the same shape.
+func NewLockingTreeConcurrent(keys []uint, workers int) *LockingTree {
+	if workers < 2 || len(keys) < workers*2 {
+		return NewLockingTreeWithKeys(keys)
+	}
+
+	// choose pivots splitting the unique keys into evenly sized ranges
+	sorted := make([]uint, len(keys))
+	copy(sorted, keys)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	unique := sorted[:0]
+	for i, k := range sorted {
+		if i == 0 || k != sorted[i-1] {
+			unique = append(unique, k)
+		}
+	}
+	if len(unique) < workers*2 {
+		return NewLockingTreeWithKeys(keys)
+	}
+	pivots := make([]uint, workers-1)
+	for i := range pivots {
+		pivots[i] = unique[(i+1)*len(unique)/workers]
+	}
+
+	// distribute the remaining keys to their ranges, preserving their relative order
+	parts := make([][]uint, workers)
+	for _, k := range keys {
+		i := sort.Search(len(pivots), func(i int) bool { return pivots[i] >= k })
+		if i < len(pivots) && pivots[i] == k {
+			continue
+		}
+		parts[i] = append(parts[i], k)
+	}
+
+	subtrees := make([]*treeNode, workers)
+	wg := new(sync.WaitGroup)
+	wg.Add(workers)
+	for i := range parts {
+		go func(i int) {
+			defer wg.Done()
+			var root *treeNode
+			for _, k := range parts[i] {
+				if root == nil {
+					root = newTreeNode(k, k, 1, NodeSideRoot, nil, nil, nil)
+				} else {
+					root.Put(k, k)
+				}
+			}
+			subtrees[i] = root
+		}(i)
+	}
+	wg.Wait()
+
+	lt := NewLockingTree()
+	lt.plant(buildBalanced(pivots, func(i int) interface{} { return pivots[i] }, subtrees))
+	return lt
+}
+
+// PutChecked inserts a new node or updates the value of an existing node, first verifying that inserting a new node
+// would not overflow the tree's count or the new node's depth, or exceed the limit set by WithInsertLimit.  Returns
+// ErrCountOverflow, ErrDepthOverflow, or ErrTreeFull, leaving the tree untouched, if it would.
+func (n *LockingTree) PutChecked(key uint, value interface{}) error {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	if n.full(key) {
+		return fmt.Errorf("unable to insert key %d: %w", key, ErrTreeFull)
+	}
+	if n.root != nil {
+		if parent, exists := n.root.attachPoint(key); !exists {
+			if n.root.count == ^uint(0) {
+				return fmt.Errorf("unable to insert key %d: %w", key, ErrCountOverflow)
+			}
+			if parent.depth == ^uint(0) {
+				return fmt.Errorf("unable to insert key %d beneath key %d: %w", key, parent.key, ErrDepthOverflow)
+			}
+		}
+	}
+	n.put(key, value, false)
+	return nil
+}
+
+// Update sets the value of the node with the provided key to the value returned by fn, inserting the key if it is
+// absent.  fn receives the current value and whether the key is present, and is called while the write lock is held,
+// so it must not call any method of this tree.  If the key is absent and this tree is at the limit set by
+// WithInsertLimit, fn is not called and the tree is left untouched.
+func (n *LockingTree) Update(key uint, fn func(value interface{}, exists bool) interface{}) {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	var (
+		value  interface{}
+		exists bool
+	)
+	if n.root != nil {
+		if tn := n.root.find(key); tn != nil {
+			value, exists = tn.value, true
+		}
+	}
+	if !exists && n.full(key) {
+		return
+	}
+	n.inCallback(func() { value = fn(value, exists) })
+	n.put(key, value, false)
+}
+
+// GetOrLoad attempts to retrieve a node by key, calling loader to obtain a value on a miss.  If loader returns true the
+// value is inserted and its node returned, otherwise nothing is inserted and false is returned.
+//
+// loader is called without any lock held, so it may be slow, or call back into this tree, without blocking other
+// callers.  As a result concurrent misses of the same key may each call loader; the first to insert wins, and every
+// caller is returned its node, with the values of the others being discarded.  If the key is absent and this tree is
+// at the limit set by WithInsertLimit, the loaded value is discarded and false is returned.
+func (n *LockingTree) GetOrLoad(key uint, loader func(uint) (interface{}, bool)) (*Node, bool) {
+	key = n.normalizeKey(key)
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	var tn *treeNode
+	if n.root != nil {
+		n.countComparisons(key)
+		tn = n.root.find(key)
+	}
+	n.mu.RUnlock()
+	if tn != nil {
+		return tn.Node, true
+	}
+	value, ok := loader(key)
+	if !ok {
+		return nil, false
+	}
+	n.lock()
+	defer n.mu.Unlock()
+	// another caller may have inserted the key while loader ran
+	if n.root != nil {
+		if tn := n.root.find(key); tn != nil {
+			return tn.Node, true
+		}
+	}
+	if !n.put(key, value, false) {
+		return nil, false
+	}
+	return n.root.find(key).Node, true
+}
+
+// PutWithNeighbors inserts a new node or updates the value of an existing node, as Put, returning the nodes with the
+// next lower and next higher keys once it is in place.  Either is nil if there is no such key.  Both are located while
+// the write lock is still held, so they are exactly the neighbors the key was given.  If the key is refused by the
+// limit set by WithInsertLimit, the neighbors it would have had are returned.
+func (n *LockingTree) PutWithNeighbors(key uint, value interface{}) (prev, next *Node) {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	var p, s *treeNode
+	if n.full(key) {
+		p, s = n.root.floor(key), n.root.ceiling(key)
+	} else {
+		n.put(key, value, false)
+		tn := n.root.find(key)
+		p, s = tn.predecessor(), tn.successor()
+	}
+	if p != nil {
+		prev = p.Node
+	}
+	if s != nil {
+		next = s.Node
+	}
+	return prev, next
+}
+
+// PutAt inserts a new node as the side child of the node with key parentKey, allowing exact shapes to be built
+// deliberately.  To place the root of an empty tree, side must be NodeSideRoot, and parentKey is ignored.
+//
+// Returns ErrKeyNotFound if the parent is absent, ErrSlotOccupied if the position already holds a node,
+// ErrOrderViolation if key is already present or does not belong at that position, or ErrTreeFull if this tree is at
+// the limit set by WithInsertLimit.  As the ordering of a binary search tree admits exactly one position for any absent
+// key, PutAt succeeds only where Put would have placed the key, and so serves to assert the shape being built.  Both
+// parentKey and key are normalized before use, see WithKeyNormalizer.
+//
+// A tree built WithRandomizedInsertPriority rotates each new node according to its priority, so would not keep it at
+// the requested position.  PutAt refuses to insert into such a tree, returning ErrPriorityShaped.
+func (n *LockingTree) PutAt(parentKey uint, side NodeSide, key uint, value interface{}) error {
+	if n.treap {
+		return fmt.Errorf("unable to place key %d: %w", key, ErrPriorityShaped)
+	}
+	parentKey, key = n.normalizeKey(parentKey), n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	if side == NodeSideRoot {
+		if n.root != nil {
+			return fmt.Errorf("unable to place key %d at the root: %w", key, ErrSlotOccupied)
+		}
+		n.put(key, value, false)
+		return nil
+	}
+	if side != NodeSideLeft && side != NodeSideRight {
+		return fmt.Errorf("unable to place key %d on side %s", key, side)
+	}
+	var parent *treeNode
+	if n.root != nil {
+		parent = n.root.find(parentKey)
+	}
+	if parent == nil {
+		return fmt.Errorf("parent key %d: %w", parentKey, ErrKeyNotFound)
+	}
+	if (side == NodeSideLeft && parent.left != nil) || (side == NodeSideRight && parent.right != nil) {
+		return fmt.Errorf("unable to place key %d %s of key %d: %w", key, side, parentKey, ErrSlotOccupied)
+	}
+	if at, exists := n.root.attachPoint(key); exists {
+		return fmt.Errorf("key %d is already present: %w", key, ErrOrderViolation)
+	} else if at != parent || (side == NodeSideLeft) != (key < parent.key) {
+		return fmt.Errorf("unable to place key %d %s of key %d: %w", key, side, parentKey, ErrOrderViolation)
+	}
+	if n.full(key) {
+		return fmt.Errorf("unable to place key %d: %w", key, ErrTreeFull)
+	}
+	n.put(key, value, false)
+	return nil
+}
+
+// Increment adds delta to the uint value of the node with the provided key under the write lock, inserting the key
+// with a value of delta if it is absent, and returns the value stored.  The total is stored exactly as Put would store
+// it, so a collision resolver sees it as the incoming value, and the value returned is the one the resolver chose.
+// Returns an error, leaving the tree untouched, if the existing value is not a uint, or ErrTreeFull if the key is
+// absent and this tree is at the limit set by WithInsertLimit.  An error is also returned if a collision resolver
+// chose a value that is not a uint, though that value remains stored.  Overflow wraps, as with any uint addition.
+func (n *LockingTree) Increment(key uint, delta uint) (uint, error) {
+	key = n.normalizeKey(key)
+	n.lock()
+	defer n.mu.Unlock()
+	if n.full(key) {
+		return 0, fmt.Errorf("unable to insert key %d: %w", key, ErrTreeFull)
+	}
+	total := delta
+	if n.root != nil {
+		if tn := n.root.find(key); tn != nil {
+			v, ok := tn.value.(uint)
+			if !ok {
+				return 0, fmt.Errorf("value for key %d must be uint, saw %T", key, tn.value)
+			}
+			total += v
+		}
+	}
+	n.put(key, total, false)
+	// a collision resolver may have stored a value other than total
+	stored, ok := n.root.find(key).value.(uint)
+	if !ok {
+		return 0, fmt.Errorf("collision resolver stored a value for key %d that is not a uint", key)
+	}
+	return stored, nil
+}
+
+// snapshot returns every node of this tree in ascending key order.  As a node's exported representation is replaced
+// rather than modified whenever it changes, the result remains safe to use after the lock is released.
+func (n *LockingTree) snapshot() []*Node {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+	nodes := make([]*Node, 0, n.root.count)
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		nodes = append(nodes, tn.Node)
+		return true
+	})
+	return nodes
+}
+
+// newBalancedLockingTree populates lt, which must be empty, with a height-balanced tree holding the keys and values of
+// the provided nodes, which must be in strictly ascending key order
+func newBalancedLockingTree(lt *LockingTree, nodes []*Node) *LockingTree {
+	if len(nodes) == 0 {
+		return lt
+	}
+	keys := make([]uint, len(nodes))
+	for i, node := range nodes {
+		keys[i] = node.key
+	}
+	lt.plant(buildBalanced(keys, func(i int) interface{} { return nodes[i].value }, nil))
+	return lt
+}
+
+// NewBalancedTreeWithSortedKeys constructs a height-balanced tree from a list of keys in strictly ascending order.  The
+// value of each node will be that of the key of that node.  With WithKeyNormalizer, each key is normalized first, and
+// the normalized keys must be in strictly ascending order.  The keys are not verified, and a tree built from keys out
+// of order will not be searchable; see NewBalancedTreeWithSortedKeysChecked.  With WithInsertLimit, keys beyond the
+// limit are dropped, as Put would drop them.
+func NewBalancedTreeWithSortedKeys(keys []uint, opts ...LockingTreeOption) *LockingTree {
+	lt := NewLockingTree(opts...)
+	if lt.insertLimit > 0 && uint(len(keys)) > lt.insertLimit {
+		keys = keys[:lt.insertLimit]
+	}
+	lt.plantSorted(keys, lt.normalizeKeys(keys))
+	return lt
+}
+
+// NewBalancedTreeWithSortedKeysChecked behaves as NewBalancedTreeWithSortedKeys, first verifying that keys are in
+// strictly ascending order once normalized.  Returns ErrUnsortedKeys if a key is equal to or less than the key before
+// it, including where two keys normalize to the same key, or ErrTreeFull if there are more keys than the limit set by
+// WithInsertLimit.
+func NewBalancedTreeWithSortedKeysChecked(keys []uint, opts ...LockingTreeOption) (*LockingTree, error) {
+	lt := NewLockingTree(opts...)
+	if lt.insertLimit > 0 && uint(len(keys)) > lt.insertLimit {
+		return nil, fmt.Errorf("unable to build a tree of %d keys: %w", len(keys), ErrTreeFull)
+	}
+	normalized := lt.normalizeKeys(keys)
+	for i := 1; i < len(normalized); i++ {
+		if normalized[i] <= normalized[i-1] {
+			return nil, fmt.Errorf("key %d at index %d follows key %d: %w", normalized[i], i, normalized[i-1], ErrUnsortedKeys)
+		}
+	}
+	lt.plantSorted(keys, normalized)
+	return lt, nil
+}
+
+// plantSorted fills this empty tree with a height-balanced tree of the provided normalized keys, which must be in
+// strictly ascending order, each given the value of the matching key as it was provided
+func (n *LockingTree) plantSorted(keys, normalized []uint) {
+	if len(keys) == 0 {
+		return
+	}
+	n.plant(buildBalanced(normalized, func(i int) interface{} { return keys[i] }, nil))
+}
+
+// Chan returns a channel that emits every node of this tree in ascending key order, closing once all nodes have been
+// sent or ctx is cancelled.  The tree is snapshotted under the read lock before Chan returns, so a slow consumer never
+// holds up writers and will not observe changes made after the call.
+func (n *LockingTree) Chan(ctx context.Context) <-chan *Node {
+	nodes := n.snapshot()
+	ch := make(chan *Node)
+	go func() {
+		defer close(ch)
+		for _, node := range nodes {
+			// select chooses randomly when both cases are ready, so check for cancellation up front
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case ch <- node:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Floor returns the node with the greatest key less than or equal to the provided key, or false if there is none
+func (n *LockingTree) Floor(key uint) (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || key < n.root.loKey {
+		return nil, false
+	}
+	return n.root.floor(key).Node, true
+}
+
+// Ceiling returns the node with the lowest key greater than or equal to the provided key, or false if there is none
+func (n *LockingTree) Ceiling(key uint) (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || key > n.root.hiKey {
+		return nil, false
+	}
+	return n.root.ceiling(key).Node, true
+}
+
+// FloorValue returns the value of the node located by Floor, or false if there is none
+func (n *LockingTree) FloorValue(key uint) (interface{}, bool) {
+	if node, ok := n.Floor(key); ok {
+		return node.value, true
+	}
+	return nil, false
+}
+
+// CeilingValue returns the value of the node located by Ceiling, or false if there is none
+func (n *LockingTree) CeilingValue(key uint) (interface{}, bool) {
+	if node, ok := n.Ceiling(key); ok {
+		return node.value, true
+	}
+	return nil, false
+}
+
+// Keys returns every key within this tree in ascending order.
+//
+// The result is cached until the next insertion or deletion, so repeated calls without intervening writes are O(1)
+// after the first.  The tradeoff is that the cached slice retains one uint per node for as long as the tree goes
+// unmodified.  The returned slice is shared between callers and MUST NOT be modified.
+func (n *LockingTree) Keys() []uint {
+	if n == nil {
+		return make([]uint, 0)
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	n.keysMu.Lock()
+	defer n.keysMu.Unlock()
+	if n.keys != nil {
+		return n.keys
+	}
+	if n.root == nil {
+		n.keys = make([]uint, 0)
+		return n.keys
+	}
+	n.keys = make([]uint, 0, n.root.count)
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		n.keys = append(n.keys, tn.key)
+		return true
+	})
+	return n.keys
+}
+
+// Entries returns the key and value of every node within this tree in ascending key order, gathered in a single
+// traversal so that each pair is consistent with the other
+func (n *LockingTree) Entries() []Entry {
+	if n == nil {
+		return make([]Entry, 0)
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return make([]Entry, 0)
+	}
+	return n.root.entries()
+}
+
+// GetNearest returns the node whose key is numerically closest to the provided key, which is the node with that key
+// if it exists.  Ties are broken in favor of the lower key.  Returns false only if this tree is empty.
+func (n *LockingTree) GetNearest(key uint) (*Node, bool) {
+	key = n.normalizeKey(key)
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	floor, ceil := n.root.floor(key), n.root.ceiling(key)
+	if floor == nil {
+		return ceil.Node, true
+	} else if ceil == nil || key-floor.key <= ceil.key-key {
+		return floor.Node, true
+	}
+	return ceil.Node, true
+}
+
+// Rank returns the zero-based position the provided key holds, or would hold, in ascending key order, which is the
+// number of keys lower than it.  The second return value reports whether the key is present.  This is O(height).
+func (n *LockingTree) Rank(key uint) (uint, bool) {
+	if n == nil {
+		return 0, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0, false
+	}
+	r, tn := n.root.rank(key)
+	return r, tn != nil
+}
+
+// GetWithRank attempts to retrieve a node by key along with its zero-based position in ascending key order, in a
+// single O(height) descent.  Returns false if the key is absent.
+func (n *LockingTree) GetWithRank(key uint) (*Node, uint, bool) {
+	key = n.normalizeKey(key)
+	if n == nil {
+		return nil, 0, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, 0, false
+	}
+	r, tn := n.root.rank(key)
+	if tn == nil {
+		return nil, 0, false
+	}
+	return tn.Node, r, true
+}
+
+// KeyIndex returns the zero-based index of the provided key within the slice returned by Keys, or the index at which
+// it would be inserted, along with whether the key is present.  It is equivalent to Rank, but performs a binary
+// search over the cached Keys slice.
+func (n *LockingTree) KeyIndex(key uint) (int, bool) {
+	keys := n.Keys()
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] >= key })
+	return i, i < len(keys) && keys[i] == key
+}
+
+// InsertionSide reports where a node with the provided key would be attached if it were inserted: beneath parent, on
+// the given side.  If this tree is empty parent is nil and side is ROOT.  If the key already exists, exists is true
+// and parent and side describe the existing node's position instead.
+func (n *LockingTree) InsertionSide(key uint) (parent *Node, side NodeSide, exists bool) {
+	key = n.normalizeKey(key)
+	if n == nil {
+		return nil, NodeSideRoot, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, NodeSideRoot, false
+	}
+	tn, exists := n.root.attachPoint(key)
+	if exists {
+		if tn.parent == nil {
+			return nil, tn.side, true
+		}
+		return tn.parent.Node, tn.side, true
+	}
+	if key < tn.key {
+		return tn.Node, NodeSideLeft, false
+	}
+	return tn.Node, NodeSideRight, false
+}
+
+// DeepestNode returns the node at the greatest depth, preferring the lowest key when several share that depth, or
+// false if this tree is empty.  The search follows each node's recorded maximum depth, visiting one node per level
+// rather than the whole tree.
+func (n *LockingTree) DeepestNode() (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	return n.root.deepest().Node, true
+}
+
+// Min returns the node with the lowest key, or false if this tree is empty
+func (n *LockingTree) Min() (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	return n.root.lowest().Node, true
+}
+
+// Max returns the node with the highest key, or false if this tree is empty
+func (n *LockingTree) Max() (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	return n.root.highest().Node, true
+}
+
+// KthSmallest returns the node with the k-th lowest key, where k is 1-based, or false if k is 0 or greater than Count.
+// This is O(height).
+func (n *LockingTree) KthSmallest(k uint) (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || k == 0 || k > n.root.count {
+		return nil, false
+	}
+	return n.root.selectRank(k - 1).Node, true
+}
+
+// Page returns up to limit nodes in ascending key order, beginning after the lowest offset nodes.  The first node is
+// located by rank in O(height), with each following node reached through its in-order successor.  Returns nil if
+// offset is beyond the end of this tree or limit is 0.
+func (n *LockingTree) Page(offset, limit uint) []*Node {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || offset >= n.root.count || limit == 0 {
+		return nil
+	}
+	if rem := n.root.count - offset; limit > rem {
+		limit = rem
+	}
+	nodes := make([]*Node, 0, limit)
+	for tn := n.root.selectRank(offset); tn != nil && uint(len(nodes)) < limit; tn = tn.successor() {
+		nodes = append(nodes, tn.Node)
+	}
+	return nodes
+}
+
+// KthLargest returns the node with the k-th highest key, where k is 1-based, or false if k is 0 or greater than Count.
+// This is O(height).
+func (n *LockingTree) KthLargest(k uint) (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || k == 0 || k > n.root.count {
+		return nil, false
+	}
+	return n.root.selectRank(n.root.count - k).Node, true
+}
+
+// RandomNode returns a node chosen uniformly at random, or false if this tree is empty.  A random rank is drawn from r
+// and located through the subtree counts, so this is O(height).  Passing a seeded r makes the choice reproducible; a
+// nil r draws from the math/rand package's default source.
+func (n *LockingTree) RandomNode(r *rand.Rand) (*Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil, false
+	}
+	var i int64
+	if r != nil {
+		i = r.Int63n(int64(n.root.count))
+	} else {
+		i = rand.Int63n(int64(n.root.count))
+	}
+	return n.root.selectRank(uint(i)).Node, true
 }
@@ -0,0 +1,198 @@
+package gerbst
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLockingTree_SelfCheck_Corrupt(t *testing.T) {
+	t.Run("misplaced_key", func(t *testing.T) {
+		lt := NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+		// 95 belongs to the right of 90, so neither walk can reach it from here
+		tn := lt.root.find(82)
+		tn.Node = newNode(95, tn.value, tn.depth, tn.side)
+		tn.loKey, tn.hiKey = 95, 95
+		if err := lt.SelfCheck(); err == nil {
+			t.Log("Expected self check to fail on a misplaced key")
+			t.Fail()
+		}
+	})
+
+	t.Run("bad_count", func(t *testing.T) {
+		lt := NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+		lt.root.left.count++
+		if err := lt.SelfCheck(); err == nil {
+			t.Log("Expected self check to fail on a bad count")
+			t.Fail()
+		}
+	})
+
+	t.Run("lookup_mismatch", func(t *testing.T) {
+		lt := NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+		if err := lt.checkLookups(lt.root.Get, lt.root.GetRecurse); err != nil {
+			t.Logf("Expected lookups of an intact tree to agree, saw %v", err)
+			t.FailNow()
+		}
+
+		// Get and GetRecurse share their comparisons, so no corruption of the shared tree can split them.  Instead the
+		// recursive walk is given a view of the tree in which node 90 has lost its left branch.
+		root, right := *lt.root, *lt.root.right
+		right.left = nil
+		root.right = &right
+		err := lt.checkLookups(lt.root.Get, root.GetRecurse)
+		if !errors.Is(err, errLookupMismatch) {
+			t.Logf("Expected diverging lookups to return errLookupMismatch, saw %v", err)
+			t.FailNow()
+		}
+		if !strings.Contains(err.Error(), "on key 82:") {
+			t.Logf("Expected the mismatch to be reported on key 82, saw %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_PutChecked_Overflow(t *testing.T) {
+	t.Run("count", func(t *testing.T) {
+		lt := NewLockingTreeWithKeys([]uint{12, 11, 90})
+		lt.root.count = ^uint(0)
+		if err := lt.PutChecked(50, 50); !errors.Is(err, ErrCountOverflow) {
+			t.Logf("Expected ErrCountOverflow, saw %v", err)
+			t.Fail()
+		}
+		if _, ok := lt.Get(50); ok {
+			t.Log("Expected key 50 not to be inserted")
+			t.Fail()
+		}
+		// updating an existing key never grows the tree
+		if err := lt.PutChecked(11, "eleven"); err != nil {
+			t.Logf("Expected update to succeed, saw %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		lt := NewLockingTreeWithKeys([]uint{12, 11, 90})
+		lt.root.left.Node = newNode(11, 11, ^uint(0), NodeSideLeft)
+		if err := lt.PutChecked(5, 5); !errors.Is(err, ErrDepthOverflow) {
+			t.Logf("Expected ErrDepthOverflow, saw %v", err)
+			t.Fail()
+		}
+		// the right side is still shallow
+		if err := lt.PutChecked(95, 95); err != nil {
+			t.Logf("Expected insert on the shallow side to succeed, saw %v", err)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_Destroy(t *testing.T) {
+	lt := NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9, 95, 100, 1})
+	var nodes []*treeNode
+	lt.root.walkPreOrder(func(tn *treeNode) bool {
+		nodes = append(nodes, tn)
+		return true
+	})
+	retained, _ := lt.Get(82)
+
+	lt.Destroy()
+
+	if lt.root != nil || lt.Count() != 0 {
+		t.Logf("Expected tree to be empty after destroy, saw count %d", lt.Count())
+		t.Fail()
+	}
+	for _, tn := range nodes {
+		if tn.parent != nil || tn.left != nil || tn.right != nil {
+			t.Logf("Expected key %d to be severed from every other node, saw %s", tn.key, tn.metaString())
+			t.Fail()
+		}
+	}
+	if retained.Key() != 82 || retained.Value() != uint(82) {
+		t.Logf("Expected previously returned node to remain valid, saw %v", retained)
+		t.Fail()
+	}
+
+	lt.Put(5, 5)
+	if err := lt.SelfCheck(); err != nil || lt.Count() != 1 {
+		t.Logf("Expected destroyed tree to remain usable, saw count %d and %v", lt.Count(), err)
+		t.Fail()
+	}
+	lt.Destroy()
+	lt.Destroy()
+}
+
+func TestMigrateTree_FutureVersion(t *testing.T) {
+	lt := NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	v1, err := lt.MarshalBinary()
+	if err != nil {
+		t.Logf("Error marshalling tree: %v", err)
+		t.FailNow()
+	}
+
+	// simulate a version 2 format whose upgrade step rewrites nothing but the version
+	var stepped int
+	binaryMigrations = append(binaryMigrations, func(data []byte) ([]byte, error) {
+		stepped++
+		return data, nil
+	})
+	defer func() { binaryMigrations = binaryMigrations[:len(binaryMigrations)-1] }()
+
+	if _, err := UnmarshalBinaryTree(v1); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Logf("Expected version 1 data to be rejected once version 2 is current, saw %v", err)
+		t.Fail()
+	}
+	v2, err := MigrateTree(v1)
+	if err != nil {
+		t.Logf("Error migrating version 1 data: %v", err)
+		t.FailNow()
+	}
+	if stepped != 1 || v2[0] != 2 || v1[0] != 1 {
+		t.Logf("Expected one step to produce version 2 without altering the input, saw %d steps, versions %d and %d", stepped, v1[0], v2[0])
+		t.Fail()
+	}
+	dst, err := UnmarshalBinaryTree(v2)
+	if err != nil {
+		t.Logf("Error unmarshalling migrated data: %v", err)
+		t.FailNow()
+	}
+	if dst.StringTree() != lt.StringTree() {
+		t.Log("Expected migrated data to decode to the source tree")
+		t.Logf("Expected:\n%s", lt.StringTree())
+		t.Logf("Actual:\n%s", dst.StringTree())
+		t.Fail()
+	}
+
+	// legacy data migrates through both steps
+	v0 := append([]byte(nil), v1...)
+	v0[0] = 0
+	if data, err := MigrateTree(v0); err != nil || data[0] != 2 || stepped != 2 {
+		t.Logf("Expected version 0 data to migrate to version 2, saw %v after %d steps", err, stepped)
+		t.Fail()
+	}
+}
+
+func TestBalanceDSW_Rotations(t *testing.T) {
+	r := rand.New(rand.NewSource(205))
+	for size := 1; size <= 200; size++ {
+		lt := NewLockingTree()
+		for _, k := range r.Perm(size) {
+			lt.Put(uint(k), nil)
+		}
+		expected := dswRotations(lt.root.count, lt.root.spineLength())
+		root, rotations := balanceDSW(lt.root)
+		if rotations != expected {
+			t.Logf("Expected %d rotations for %d nodes, saw %d", expected, size, rotations)
+			t.Fail()
+		}
+		root.rebuildMeta(nil, 1, NodeSideRoot)
+		if !root.heightBalanced() {
+			t.Logf("Expected %d nodes to be balanced", size)
+			t.Fail()
+		}
+		if err := root.checkMeta(nil, 1, NodeSideRoot); err != nil {
+			t.Logf("Expected %d nodes to have valid metadata, saw %v", size, err)
+			t.Fail()
+		}
+	}
+}
@@ -1,7 +1,18 @@
 package gerbst_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/dcarbone/gerbst"
 	"github.com/dcarbone/gerbst/testutil"
@@ -36,6 +47,7 @@ func TestLockingTree(t *testing.T) {
 		t.Run("counts", testutil.BuildTestCounts(lt, true, 6, 3, 2))
 		t.Run("depths", testutil.BuildTestDepths(lt, true, 4, 4, 3))
 		t.Run("gets", testutil.BuildTestGets(lt, true, getTests))
+		t.Run("order_independence", testutil.BuildTestOrderIndependence(keys, true, 10))
 	})
 }
 
@@ -73,3 +85,2691 @@ func TestDoesItWorkAtAll(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestLockingTree_PathCost(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	if c, ok := lt.PathCost(9); !ok {
+		t.Log("Expected PathCost to find key 9")
+		t.Fail()
+	} else if c != 12+11+7+9 {
+		t.Logf("Expected PathCost(9) to be %d, saw %d", 12+11+7+9, c)
+		t.Fail()
+	}
+
+	if c, ok := lt.PathCost(12); !ok || c != 12 {
+		t.Logf("Expected PathCost(12) to be 12, saw %d (ok=%t)", c, ok)
+		t.Fail()
+	}
+
+	if _, ok := lt.PathCost(8); ok {
+		t.Log("Expected PathCost to return false for absent key 8")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_ContainsAllAny(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := []struct {
+		keys []uint
+		all  bool
+		any  bool
+	}{
+		{keys: []uint{12, 7, 82}, all: true, any: true},
+		{keys: []uint{12, 8, 82}, all: false, any: true},
+		{keys: []uint{1, 8, 100}, all: false, any: false},
+		{keys: []uint{}, all: true, any: false},
+	}
+
+	for _, tt := range tests {
+		if v := lt.ContainsAll(tt.keys); v != tt.all {
+			t.Logf("Expected ContainsAll(%v) to be %t, saw %t", tt.keys, tt.all, v)
+			t.Fail()
+		}
+		if v := lt.ContainsAny(tt.keys); v != tt.any {
+			t.Logf("Expected ContainsAny(%v) to be %t, saw %t", tt.keys, tt.any, v)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_GetMany(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	lt.Put(82, "eighty-two")
+
+	values := lt.GetMany([]uint{12, 8, 82, 1, 100, 9, 12})
+	expected := map[uint]interface{}{12: uint(12), 82: "eighty-two", 9: uint(9)}
+	if len(values) != len(expected) {
+		t.Logf("Expected %d values, saw %v", len(expected), values)
+		t.Fail()
+	}
+	for k, v := range expected {
+		if got, ok := values[k]; !ok || got != v {
+			t.Logf("Expected key %d to map to %v, saw %v", k, v, got)
+			t.Fail()
+		}
+	}
+
+	if v := lt.GetMany(nil); v == nil || len(v) != 0 {
+		t.Logf("Expected no keys to return an empty map, saw %v", v)
+		t.Fail()
+	}
+	if v := gerbst.NewLockingTree().GetMany([]uint{1, 2}); v == nil || len(v) != 0 {
+		t.Logf("Expected empty tree to return an empty map, saw %v", v)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_WouldAdd(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	before := lt.StringTree()
+
+	tests := []struct {
+		keys     []uint
+		expected uint
+	}{
+		{keys: []uint{12, 13, 90, 91, 7, 8}, expected: 3},
+		{keys: []uint{13, 13, 12, 13, 14, 14}, expected: 2},
+		{keys: []uint{12, 11, 90, 82, 7, 9}, expected: 0},
+		{keys: nil, expected: 0},
+	}
+	for _, tt := range tests {
+		if c := lt.WouldAdd(tt.keys); c != tt.expected {
+			t.Logf("Expected %v to add %d keys, saw %d", tt.keys, tt.expected, c)
+			t.Fail()
+		}
+	}
+	if after := lt.StringTree(); after != before {
+		t.Log("Expected WouldAdd to leave the tree unmodified")
+		t.Fail()
+	}
+
+	batch := []uint{12, 13, 13, 90, 91}
+	expected := lt.Count() + lt.WouldAdd(batch)
+	for _, k := range batch {
+		lt.Put(k, k)
+	}
+	if c := lt.Count(); c != expected {
+		t.Logf("Expected putting the batch to reach count %d, saw %d", expected, c)
+		t.Fail()
+	}
+
+	// keys sharing a bucket would share a node
+	buckets := gerbst.NewLockingTreeWithKeys([]uint{10}, gerbst.WithKeyNormalizer(func(k uint) uint { return k / 10 * 10 }))
+	if c := buckets.WouldAdd([]uint{11, 15, 21, 29, 30}); c != 2 {
+		t.Logf("Expected normalized keys to add 2 buckets, saw %d", c)
+		t.Fail()
+	}
+
+	var nilTree *gerbst.LockingTree
+	if c := nilTree.WouldAdd([]uint{1, 1, 2}); c != 2 {
+		t.Logf("Expected nil tree to count 2 distinct keys, saw %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_MapValues(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+	before := lt.StringTree()
+
+	lt.MapValues(func(_ uint, old interface{}) interface{} {
+		return old.(uint) * 2
+	})
+
+	for _, k := range keys {
+		if n, ok := lt.Get(k); !ok {
+			t.Logf("Expected key %d to still exist", k)
+			t.Fail()
+		} else if v := n.Value(); v != k*2 {
+			t.Logf("Expected key %d to have value %d, saw %v", k, k*2, v)
+			t.Fail()
+		}
+	}
+
+	if c := lt.Count(); c != uint(len(keys)) {
+		t.Logf("Expected count to remain %d, saw %d", len(keys), c)
+		t.Fail()
+	}
+	if after := lt.StringTree(); after == before {
+		t.Log("Expected StringTree to reflect updated values")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Fold(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	sum := lt.Fold(uint(0), func(acc interface{}, key uint, _ interface{}) interface{} {
+		return acc.(uint) + key
+	})
+	if sum != uint(211) {
+		t.Logf("Expected folded key sum to be 211, saw %v", sum)
+		t.Fail()
+	}
+
+	order := lt.Fold([]uint(nil), func(acc interface{}, key uint, _ interface{}) interface{} {
+		return append(acc.([]uint), key)
+	}).([]uint)
+	expected := []uint{7, 9, 11, 12, 82, 90}
+	if len(order) != len(expected) {
+		t.Logf("Expected fold to visit %d keys, saw %d", len(expected), len(order))
+		t.FailNow()
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Logf("Expected fold to visit keys in order %v, saw %v", expected, order)
+			t.Fail()
+			break
+		}
+	}
+
+	if v := gerbst.NewLockingTree().Fold("init", nil); v != "init" {
+		t.Logf("Expected fold over empty tree to return initial accumulator, saw %v", v)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_DuplicateCount(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	if c := lt.DuplicateCount(); c != 0 {
+		t.Logf("Expected no duplicates after inserting unique keys, saw %d", c)
+		t.Fail()
+	}
+
+	lt.Put(13, 13)
+	lt.PutRecurse(14, 14)
+	if c := lt.DuplicateCount(); c != 0 {
+		t.Logf("Expected new keys not to count as duplicates, saw %d", c)
+		t.Fail()
+	}
+
+	lt.Put(7, "seven")
+	lt.PutRecurse(12, "twelve")
+	lt.Put(7, "seven again")
+	if c := lt.DuplicateCount(); c != 3 {
+		t.Logf("Expected 3 duplicates, saw %d", c)
+		t.Fail()
+	}
+
+	// only Put and PutRecurse count, not the other ways of writing to an existing key
+	lt.Update(90, func(v interface{}, _ bool) interface{} { return v })
+	_ = lt.PutChecked(82, "checked")
+	_ = lt.PutErr(82, "err")
+	lt.PutWithNeighbors(11, "neighbors")
+	if c := lt.DuplicateCount(); c != 3 {
+		t.Logf("Expected updates outside of Put and PutRecurse not to count as duplicates, saw %d", c)
+		t.Fail()
+	}
+
+	full := gerbst.NewLockingTreeWithKeys([]uint{1}, gerbst.WithInsertLimit(1))
+	full.Put(2, 2)
+	if c := full.DuplicateCount(); c != 0 {
+		t.Logf("Expected a refused key not to count as a duplicate, saw %d", c)
+		t.Fail()
+	}
+
+	if prev := lt.ResetDuplicateCount(); prev != 3 {
+		t.Logf("Expected reset to return 3, saw %d", prev)
+		t.Fail()
+	}
+	if c := lt.DuplicateCount(); c != 0 {
+		t.Logf("Expected duplicates to be 0 after reset, saw %d", c)
+		t.Fail()
+	}
+
+	if n, ok := lt.Get(7); !ok || n.Depth() != 3 || n.Side() != gerbst.NodeSideLeft {
+		t.Logf("Expected updated node 7 to keep depth 3 and side LEFT, saw %v", n)
+		t.Fail()
+	}
+}
+
+func TestNewLockingTreeWithKeysValue(t *testing.T) {
+	type marker struct{ name string }
+
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	shared := &marker{name: "shared"}
+	lt := gerbst.NewLockingTreeWithKeysValue(keys, shared)
+
+	t.Run("counts", testutil.BuildTestCounts(lt, false, 6, 3, 2))
+	t.Run("depths", testutil.BuildTestDepths(lt, false, 4, 4, 3))
+
+	for _, k := range keys {
+		if n, ok := lt.Get(k); !ok {
+			t.Logf("Expected key %d to exist", k)
+			t.Fail()
+		} else if v := n.Value(); v != shared {
+			t.Logf("Expected key %d to have shared value, saw %v", k, v)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_Diameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []uint
+		diameter uint
+	}{
+		{name: "empty", keys: nil, diameter: 0},
+		{name: "single", keys: []uint{1}, diameter: 0},
+		// 9 -> 7 -> 11 -> 12 -> 90 -> 82
+		{name: "sample", keys: []uint{12, 11, 90, 82, 7, 9}, diameter: 5},
+		// leaf to leaf across the root of a perfect tree of height 3
+		{name: "balanced", keys: []uint{4, 2, 6, 1, 3, 5, 7}, diameter: 4},
+		// longest path sits entirely within the left branch
+		{name: "off_root", keys: []uint{100, 50, 25, 75, 10, 90, 5, 95}, diameter: 6},
+	}
+
+	for _, tt := range tests {
+		if d := gerbst.NewLockingTreeWithKeys(tt.keys).Diameter(); d != tt.diameter {
+			t.Logf("Expected %s tree to have diameter %d, saw %d", tt.name, tt.diameter, d)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_ApproxSizeBytes(t *testing.T) {
+	empty := gerbst.NewLockingTree().ApproxSizeBytes()
+	one := gerbst.NewLockingTreeWithKeys([]uint{1}).ApproxSizeBytes()
+	perNode := one - empty
+	if perNode == 0 {
+		t.Log("Expected a single node to add to the estimate")
+		t.FailNow()
+	}
+
+	for _, c := range []uint{10, 100, 1000} {
+		keys := make([]uint, c)
+		for i := range keys {
+			keys[i] = uint(i)
+		}
+		if s := gerbst.NewLockingTreeWithKeys(keys).ApproxSizeBytes(); s != empty+uintptr(c)*perNode {
+			t.Logf("Expected tree of %d nodes to estimate %d bytes, saw %d", c, empty+uintptr(c)*perNode, s)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_StringTreeASCII(t *testing.T) {
+	const expectedTree = `ROOT[12(12)]
++-- LEFT[11(11)]
+|   |-- LEFT[7(7)]
+|       +-- RIGHT[9(9)]
++-- RIGHT[90(90)]
+    +-- LEFT[82(82)]
+`
+
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	st := lt.StringTreeASCII()
+
+	for i := 0; i < len(st); i++ {
+		if st[i] > 127 {
+			t.Logf("Expected only ASCII bytes, saw %q at offset %d", st[i], i)
+			t.Fail()
+			break
+		}
+	}
+
+	if st != expectedTree {
+		t.Log("Tree did not match expected")
+		t.Logf("Expected:\n%s", expectedTree)
+		t.Logf("Actual:\n%s", st)
+		t.Fail()
+	}
+
+	if st := gerbst.NewLockingTree().StringTreeASCII(); st != "" {
+		t.Logf("Expected empty tree to render as empty string, saw %q", st)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Rekey(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+
+	t.Run("offset", func(t *testing.T) {
+		rk, err := lt.Rekey(func(old uint) uint { return old + 100 })
+		if err != nil {
+			t.Logf("Unexpected error: %v", err)
+			t.FailNow()
+		}
+
+		t.Run("counts", testutil.BuildTestCounts(rk, false, 6, 3, 2))
+		t.Run("depths", testutil.BuildTestDepths(rk, false, 4, 4, 3))
+
+		for _, k := range keys {
+			if n, ok := rk.Get(k + 100); !ok {
+				t.Logf("Expected rekeyed tree to contain key %d", k+100)
+				t.Fail()
+			} else if v := n.Value(); v != k {
+				t.Logf("Expected key %d to retain value %d, saw %v", k+100, k, v)
+				t.Fail()
+			}
+		}
+
+		if lo, hi := rk.LowestKey(), rk.HighestKey(); lo != 107 || hi != 190 {
+			t.Logf("Expected rekeyed bounds [107, 190], saw [%d, %d]", lo, hi)
+			t.Fail()
+		}
+
+		// source must be untouched
+		if _, ok := lt.Get(12); !ok {
+			t.Log("Expected source tree to be left untouched")
+			t.Fail()
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		zlt := gerbst.NewLockingTreeWithKeys(keys, gerbst.WithDepthBase(gerbst.DepthBaseZero), gerbst.WithInsertLimit(6))
+		rk, err := zlt.Rekey(func(old uint) uint { return old + 100 })
+		if err != nil {
+			t.Logf("Unexpected error: %v", err)
+			t.FailNow()
+		}
+		for _, k := range keys {
+			d, _ := zlt.Depth(k)
+			if rd, ok := rk.Depth(k + 100); !ok || d != rd {
+				t.Logf("Expected key %d to keep depth %d of key %d, saw %d", k+100, d, k, rd)
+				t.Fail()
+			}
+		}
+		rk.Rebalance()
+		if d, ok := rk.Depth(112); !ok || d != 0 {
+			t.Logf("Expected rebalanced root key 112 at depth 0, saw %d", d)
+			t.Fail()
+		}
+		if err := rk.PutErr(1, 1); err != nil || rk.InsertLimit() != 0 {
+			t.Logf("Expected rekeyed tree not to inherit the insert limit, saw %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		if _, err := lt.Rekey(func(old uint) uint { return old / 10 }); err == nil {
+			t.Log("Expected error for colliding keys")
+			t.Fail()
+		}
+	})
+
+	t.Run("non_monotonic", func(t *testing.T) {
+		if _, err := lt.Rekey(func(old uint) uint { return 1000 - old }); err == nil {
+			t.Log("Expected error for order-reversing keys")
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_TreePrinter(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tp := lt.TreePrinter()
+	if tp == nil {
+		t.Log("Expected non-nil tree printer")
+		t.FailNow()
+	}
+	if p, st := tp.Print(), lt.StringTree(); p != st {
+		t.Log("Expected TreePrinter output to match StringTree")
+		t.Logf("Expected:\n%s", st)
+		t.Logf("Actual:\n%s", p)
+		t.Fail()
+	}
+
+	// callers are free to annotate the returned tree
+	tp.Add("annotation")
+	if st := lt.StringTree(); st == tp.Print() {
+		t.Log("Expected annotating the returned printer not to affect the tree")
+		t.Fail()
+	}
+
+	if tp := gerbst.NewLockingTree().TreePrinter(); tp != nil {
+		t.Log("Expected empty tree to return nil printer")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Delete(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+
+	for _, del := range keys {
+		lt := gerbst.NewLockingTreeWithKeys(keys)
+		if n, ok := lt.Delete(del); !ok || n.Key() != del {
+			t.Logf("Expected to delete key %d, saw %v (ok=%t)", del, n, ok)
+			t.Fail()
+			continue
+		}
+		if _, ok := lt.Delete(del); ok {
+			t.Logf("Expected second delete of key %d to return false", del)
+			t.Fail()
+		}
+
+		remaining := make([]uint, 0, len(keys)-1)
+		for _, k := range keys {
+			if k != del {
+				remaining = append(remaining, k)
+			}
+		}
+		t.Run(fmt.Sprintf("gets_%d", del), testutil.BuildTestGets(lt, false, testutil.GetTestsFromKeys(remaining, []uint{del})))
+		if c := lt.Count(); c != uint(len(remaining)) {
+			t.Logf("Expected count %d after deleting %d, saw %d", len(remaining), del, c)
+			t.Fail()
+		}
+	}
+
+	t.Run("root_depths", func(t *testing.T) {
+		// deleting the root promotes 82, pulling the right branch up a level
+		lt := gerbst.NewLockingTreeWithKeys(keys)
+		lt.Delete(12)
+		t.Run("counts", testutil.BuildTestCounts(lt, false, 5, 3, 1))
+		t.Run("depths", testutil.BuildTestDepths(lt, false, 4, 4, 2))
+		if n, _ := lt.Get(90); n.Depth() != 2 || n.Side() != gerbst.NodeSideRight {
+			t.Logf("Expected key 90 at depth 2 on the right, saw %d %s", n.Depth(), n.Side())
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_SetMaxCount(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+
+	t.Run("smallest_key", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys(keys)
+		lt.SetMaxCount(6)
+		lt.Put(50, 50)
+		if c := lt.Count(); c != 6 {
+			t.Logf("Expected count to remain at 6, saw %d", c)
+			t.Fail()
+		}
+		if lt.Contains(7) {
+			t.Log("Expected smallest key 7 to be evicted")
+			t.Fail()
+		}
+		if !lt.Contains(50) {
+			t.Log("Expected key 50 to be inserted")
+			t.Fail()
+		}
+
+		// updates never evict
+		lt.Put(50, "fifty")
+		if c := lt.Count(); c != 6 || !lt.Contains(9) {
+			t.Logf("Expected update to leave all nodes in place, saw count %d", c)
+			t.Fail()
+		}
+	})
+
+	t.Run("deepest", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys(keys)
+		lt.SetEvictionPolicy(gerbst.EvictDeepest)
+		lt.SetMaxCount(6)
+		lt.Put(100, 100)
+		if lt.Contains(9) {
+			t.Log("Expected deepest key 9 to be evicted")
+			t.Fail()
+		}
+		if c := lt.Count(); c != 6 {
+			t.Logf("Expected count to remain at 6, saw %d", c)
+			t.Fail()
+		}
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys(keys)
+		lt.SetMaxCount(3)
+		if c := lt.Count(); c != 3 {
+			t.Logf("Expected lowering the cap to shrink the tree to 3, saw %d", c)
+			t.Fail()
+		}
+		for i := uint(200); i < 300; i++ {
+			lt.Put(i, i)
+			if c := lt.Count(); c > 3 {
+				t.Logf("Expected count never to exceed 3, saw %d", c)
+				t.FailNow()
+			}
+		}
+	})
+}
+
+func TestLockingTree_WithInsertLimit(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}, gerbst.WithInsertLimit(6))
+	before := lt.StringTree()
+
+	if l, m := lt.InsertLimit(), lt.MaxCount(); l != 6 || m != 0 {
+		t.Logf("Expected insert limit 6 independent of eviction cap, saw %d and %d", l, m)
+		t.Fail()
+	}
+
+	if err := lt.PutErr(50, 50); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected inserting beyond the limit to return ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+	lt.Put(51, 51)
+	lt.Update(52, func(interface{}, bool) interface{} {
+		t.Log("Expected Update not to call fn for a refused key")
+		t.Fail()
+		return nil
+	})
+	if _, err := lt.Increment(53, 1); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected Increment beyond the limit to return ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+	if err := lt.PutChecked(54, 54); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected PutChecked beyond the limit to return ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+	if err := lt.PutAt(82, gerbst.NodeSideRight, 85, 85); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected PutAt beyond the limit to return ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+	if n, ok := lt.GetOrLoad(55, func(k uint) (interface{}, bool) { return k, true }); ok {
+		t.Logf("Expected GetOrLoad beyond the limit to insert nothing, saw %v", n)
+		t.Fail()
+	}
+	if prev, next := lt.PutWithNeighbors(56, 56); prev == nil || prev.Key() != 12 || next == nil || next.Key() != 82 {
+		t.Logf("Expected refused key to report the neighbors it would have had, saw %v and %v", prev, next)
+		t.Fail()
+	}
+	if err := lt.Graft(gerbst.NewLockingTreeWithKeys([]uint{57})); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected Graft beyond the limit to return ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+	if after := lt.StringTree(); after != before {
+		t.Logf("Expected a full tree to be left untouched, saw:\n%s", after)
+		t.Fail()
+	}
+
+	if err := lt.PutErr(82, "updated"); err != nil {
+		t.Logf("Expected updating an existing key to succeed, saw %v", err)
+		t.Fail()
+	}
+	lt.Update(9, func(v interface{}, exists bool) interface{} { return v.(uint) + 1 })
+	if n, _ := lt.Get(82); n.Value() != "updated" {
+		t.Logf("Expected key 82 to be updated, saw %v", n.Value())
+		t.Fail()
+	}
+	if n, _ := lt.Get(9); n.Value() != uint(10) {
+		t.Logf("Expected key 9 to be updated, saw %v", n.Value())
+		t.Fail()
+	}
+
+	lt.Delete(7)
+	if err := lt.PutErr(50, 50); err != nil || lt.Count() != 6 {
+		t.Logf("Expected deleting a key to make room, saw %v with count %d", err, lt.Count())
+		t.Fail()
+	}
+
+	buf := new(bytes.Buffer)
+	src := gerbst.NewLockingTreeWithKeysValue([]uint{1, 2, 3}, "v")
+	if err := src.Encode(buf, encodeStringValue); err != nil {
+		t.Logf("Error encoding tree: %v", err)
+		t.FailNow()
+	}
+	small := gerbst.NewLockingTree(gerbst.WithInsertLimit(2))
+	if err := small.Decode(buf, decodeStringValue); !errors.Is(err, gerbst.ErrTreeFull) || small.Count() != 0 {
+		t.Logf("Expected decoding more nodes than the limit to return ErrTreeFull, saw %v", err)
+		t.Fail()
+	}
+
+	if err := gerbst.NewLockingTreeWithKeys([]uint{1, 2, 3}).PutErr(4, 4); err != nil {
+		t.Logf("Expected no limit by default, saw %v", err)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_SelfCheck(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected sample tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+
+	lt.Delete(12)
+	lt.Put(85, 85)
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected modified tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+
+	if err := gerbst.NewLockingTree().SelfCheck(); err != nil {
+		t.Logf("Expected empty tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+}
+
+func TestNewLockingTreeConcurrent(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := make([]uint, 10000)
+	for i := range keys {
+		keys[i] = uint(rng.Intn(20000))
+	}
+
+	seq := gerbst.NewLockingTreeWithKeys(keys)
+
+	for _, workers := range []int{1, 2, 3, 8} {
+		lt := gerbst.NewLockingTreeConcurrent(keys, workers)
+		if err := lt.SelfCheck(); err != nil {
+			t.Logf("Expected tree built with %d workers to pass self check, saw %v", workers, err)
+			t.Fail()
+		}
+		if c, sc := lt.Count(), seq.Count(); c != sc {
+			t.Logf("Expected tree built with %d workers to have count %d, saw %d", workers, sc, c)
+			t.Fail()
+		}
+		for _, k := range keys {
+			if n, ok := lt.Get(k); !ok || n.Value() != k {
+				t.Logf("Expected tree built with %d workers to contain key %d", workers, k)
+				t.Fail()
+				break
+			}
+		}
+	}
+}
+
+func benchmarkKeys(n int) []uint {
+	rng := rand.New(rand.NewSource(1))
+	keys := make([]uint, n)
+	for i := range keys {
+		keys[i] = uint(rng.Int63())
+	}
+	return keys
+}
+
+func BenchmarkNewLockingTreeWithKeys_1M(b *testing.B) {
+	keys := benchmarkKeys(1000000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gerbst.NewLockingTreeWithKeys(keys)
+	}
+}
+
+func BenchmarkNewLockingTreeConcurrent_1M(b *testing.B) {
+	keys := benchmarkKeys(1000000)
+	workers := runtime.GOMAXPROCS(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gerbst.NewLockingTreeConcurrent(keys, workers)
+	}
+}
+
+func TestLockingTree_PutChecked(t *testing.T) {
+	lt := gerbst.NewLockingTree()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		if err := lt.PutChecked(k, k); err != nil {
+			t.Logf("Unexpected error inserting key %d: %v", k, err)
+			t.Fail()
+		}
+	}
+	t.Run("counts", testutil.BuildTestCounts(lt, false, 6, 3, 2))
+	t.Run("depths", testutil.BuildTestDepths(lt, false, 4, 4, 3))
+}
+
+func TestLockingTree_WithCompactPrinting(t *testing.T) {
+	const expectedTree = `ROOT[12]
+└── LEFT[11]
+│   ├── LEFT[7(seven)]
+│       └── RIGHT[9]
+└── RIGHT[90]
+    └── LEFT[82]
+`
+
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}, gerbst.WithCompactPrinting())
+	lt.Put(7, "seven")
+	lt.Put(82, nil)
+
+	if st := lt.StringTree(); st != expectedTree {
+		t.Log("Tree did not match expected")
+		t.Logf("Expected:\n%s", expectedTree)
+		t.Logf("Actual:\n%s", st)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Chan(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	t.Run("ordered", func(t *testing.T) {
+		expected := []uint{7, 9, 11, 12, 82, 90}
+		seen := make([]uint, 0, len(expected))
+		for n := range lt.Chan(context.Background()) {
+			seen = append(seen, n.Key())
+		}
+		if fmt.Sprint(seen) != fmt.Sprint(expected) {
+			t.Logf("Expected keys %v, saw %v", expected, seen)
+			t.Fail()
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := lt.Chan(ctx)
+		if n := <-ch; n == nil || n.Key() != 7 {
+			t.Logf("Expected first node to have key 7, saw %v", n)
+			t.Fail()
+		}
+		cancel()
+
+		// at most one further node may already be in flight
+		var extra int
+		for range ch {
+			extra++
+		}
+		if extra > 1 {
+			t.Logf("Expected channel to close promptly after cancellation, saw %d more nodes", extra)
+			t.Fail()
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if _, ok := <-gerbst.NewLockingTree().Chan(context.Background()); ok {
+			t.Log("Expected channel for empty tree to be closed immediately")
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_FloorCeiling(t *testing.T) {
+	lt := gerbst.NewLockingTree()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		lt.Put(k, fmt.Sprintf("v%d", k))
+	}
+
+	tests := []struct {
+		key     uint
+		floor   interface{}
+		floorOK bool
+		ceil    interface{}
+		ceilOK  bool
+	}{
+		{key: 0, floorOK: false, ceil: "v7", ceilOK: true},
+		{key: 7, floor: "v7", floorOK: true, ceil: "v7", ceilOK: true},
+		{key: 10, floor: "v9", floorOK: true, ceil: "v11", ceilOK: true},
+		{key: 50, floor: "v12", floorOK: true, ceil: "v82", ceilOK: true},
+		{key: 83, floor: "v82", floorOK: true, ceil: "v90", ceilOK: true},
+		{key: 100, floor: "v90", floorOK: true, ceilOK: false},
+	}
+
+	for _, tt := range tests {
+		if v, ok := lt.FloorValue(tt.key); ok != tt.floorOK || v != tt.floor {
+			t.Logf("Expected FloorValue(%d) to be %v (%t), saw %v (%t)", tt.key, tt.floor, tt.floorOK, v, ok)
+			t.Fail()
+		}
+		if v, ok := lt.CeilingValue(tt.key); ok != tt.ceilOK || v != tt.ceil {
+			t.Logf("Expected CeilingValue(%d) to be %v (%t), saw %v (%t)", tt.key, tt.ceil, tt.ceilOK, v, ok)
+			t.Fail()
+		}
+	}
+
+	if _, ok := gerbst.NewLockingTree().FloorValue(1); ok {
+		t.Log("Expected FloorValue on empty tree to return false")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_WithCollisionResolver(t *testing.T) {
+	maxResolver := func(old, new interface{}) interface{} {
+		if old.(int) > new.(int) {
+			return old
+		}
+		return new
+	}
+
+	lt := gerbst.NewLockingTree(gerbst.WithCollisionResolver(maxResolver))
+	lt.Put(12, 10)
+	lt.Put(12, 5)
+	if n, _ := lt.Get(12); n.Value() != 10 {
+		t.Logf("Expected smaller value not to replace larger, saw %v", n.Value())
+		t.Fail()
+	}
+
+	lt.PutRecurse(12, 20)
+	if n, _ := lt.Get(12); n.Value() != 20 {
+		t.Logf("Expected larger value to replace smaller, saw %v", n.Value())
+		t.Fail()
+	}
+
+	lt.Put(11, 1)
+	if n, ok := lt.Get(11); !ok || n.Value() != 1 {
+		t.Log("Expected new key to be inserted without consulting the resolver")
+		t.Fail()
+	}
+	if c := lt.DuplicateCount(); c != 2 {
+		t.Logf("Expected resolved collisions to count as duplicates, saw %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Keys(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	if keys := lt.Keys(); !equalKeys(keys, []uint{7, 9, 11, 12, 82, 90}) {
+		t.Logf("Expected sorted keys, saw %v", keys)
+		t.Fail()
+	}
+
+	first, second := lt.Keys(), lt.Keys()
+	if &first[0] != &second[0] {
+		t.Log("Expected repeated calls without writes to return the cached slice")
+		t.Fail()
+	}
+
+	lt.Put(12, "update")
+	if keys := lt.Keys(); &keys[0] != &first[0] {
+		t.Log("Expected value updates not to invalidate the cache")
+		t.Fail()
+	}
+
+	lt.Put(50, 50)
+	if keys := lt.Keys(); !equalKeys(keys, []uint{7, 9, 11, 12, 50, 82, 90}) {
+		t.Logf("Expected cache to reflect insertion, saw %v", keys)
+		t.Fail()
+	}
+
+	lt.Delete(7)
+	if keys := lt.Keys(); !equalKeys(keys, []uint{9, 11, 12, 50, 82, 90}) {
+		t.Logf("Expected cache to reflect deletion, saw %v", keys)
+		t.Fail()
+	}
+
+	if keys := gerbst.NewLockingTree().Keys(); keys == nil || len(keys) != 0 {
+		t.Logf("Expected empty, non-nil keys for empty tree, saw %v", keys)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_GetNearest(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := map[uint]uint{
+		0:   7,
+		7:   7,
+		8:   7, // tie between 7 and 9 favors the lower key
+		10:  9,
+		50:  82,
+		47:  12,
+		83:  82,
+		87:  90,
+		500: 90,
+	}
+	for key, expected := range tests {
+		if n, ok := lt.GetNearest(key); !ok || n.Key() != expected {
+			t.Logf("Expected GetNearest(%d) to return key %d, saw %v (%t)", key, expected, n, ok)
+			t.Fail()
+		}
+	}
+
+	if _, ok := gerbst.NewLockingTree().GetNearest(1); ok {
+		t.Log("Expected GetNearest on empty tree to return false")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_RankKeyIndex(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	for i, k := range []uint{7, 9, 11, 12, 82, 90} {
+		r, rok := lt.Rank(k)
+		ki, kok := lt.KeyIndex(k)
+		if !rok || !kok || r != uint(i) || ki != i {
+			t.Logf("Expected key %d at index %d, saw Rank=%d (%t) KeyIndex=%d (%t)", k, i, r, rok, ki, kok)
+			t.Fail()
+		}
+	}
+
+	for _, k := range []uint{0, 8, 50, 100} {
+		r, rok := lt.Rank(k)
+		ki, kok := lt.KeyIndex(k)
+		if rok || kok || r != uint(ki) {
+			t.Logf("Expected absent key %d to agree, saw Rank=%d (%t) KeyIndex=%d (%t)", k, r, rok, ki, kok)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_InsertionSide(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := []struct {
+		key    uint
+		parent uint
+		side   gerbst.NodeSide
+		exists bool
+	}{
+		{key: 8, parent: 9, side: gerbst.NodeSideLeft},
+		{key: 10, parent: 9, side: gerbst.NodeSideRight},
+		{key: 1, parent: 7, side: gerbst.NodeSideLeft},
+		{key: 50, parent: 82, side: gerbst.NodeSideLeft},
+		{key: 100, parent: 90, side: gerbst.NodeSideRight},
+		{key: 9, parent: 7, side: gerbst.NodeSideRight, exists: true},
+	}
+	for _, tt := range tests {
+		parent, side, exists := lt.InsertionSide(tt.key)
+		if parent == nil || parent.Key() != tt.parent || side != tt.side || exists != tt.exists {
+			t.Logf("Expected key %d to attach %s of %d (exists=%t), saw %s of %v (exists=%t)",
+				tt.key, tt.side, tt.parent, tt.exists, side, parent, exists)
+			t.Fail()
+		}
+
+		// verify the prediction by actually inserting into a copy
+		if !tt.exists {
+			cp := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+			cp.Put(tt.key, tt.key)
+			if n, _ := cp.Get(tt.key); n.Side() != tt.side || n.Depth() != parent.Depth()+1 {
+				t.Logf("Expected inserted key %d to land %s at depth %d, saw %s at %d",
+					tt.key, tt.side, parent.Depth()+1, n.Side(), n.Depth())
+				t.Fail()
+			}
+		}
+	}
+
+	if parent, side, exists := lt.InsertionSide(12); parent != nil || side != gerbst.NodeSideRoot || !exists {
+		t.Logf("Expected root key to report nil parent and ROOT side, saw %v %s %t", parent, side, exists)
+		t.Fail()
+	}
+	if parent, side, exists := gerbst.NewLockingTree().InsertionSide(1); parent != nil || side != gerbst.NodeSideRoot || exists {
+		t.Logf("Expected empty tree to report nil parent and ROOT side, saw %v %s %t", parent, side, exists)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_StringTree(t *testing.T) {
+	t.Run("matches_gotree", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 20; i++ {
+			keys := make([]uint, 50)
+			for j := range keys {
+				keys[j] = uint(rng.Intn(100))
+			}
+			lt := gerbst.NewLockingTreeWithKeys(keys)
+			if st, gt := lt.StringTree(), lt.TreePrinter().Print(); st != gt {
+				t.Logf("Expected StringTree to match gotree output for keys %v", keys)
+				t.Logf("Expected:\n%s", gt)
+				t.Logf("Actual:\n%s", st)
+				t.Fail()
+			}
+		}
+	})
+
+	t.Run("deep", func(t *testing.T) {
+		const depth = 10000
+		lt := gerbst.NewLockingTree()
+		for i := uint(0); i < depth; i++ {
+			lt.Put(i, nil)
+		}
+		st := lt.StringTree()
+		if lines := strings.Count(st, "\n"); lines != depth {
+			t.Logf("Expected %d lines, saw %d", depth, lines)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_Kth(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	sorted := []uint{7, 9, 11, 12, 82, 90}
+
+	for i, k := range sorted {
+		if n, ok := lt.KthSmallest(uint(i + 1)); !ok || n.Key() != k {
+			t.Logf("Expected KthSmallest(%d) to be %d, saw %v (%t)", i+1, k, n, ok)
+			t.Fail()
+		}
+		if n, ok := lt.KthLargest(uint(len(sorted) - i)); !ok || n.Key() != k {
+			t.Logf("Expected KthLargest(%d) to be %d, saw %v (%t)", len(sorted)-i, k, n, ok)
+			t.Fail()
+		}
+	}
+
+	if mn, _ := lt.Min(); mn.Key() != 7 {
+		t.Logf("Expected Min to be 7, saw %d", mn.Key())
+		t.Fail()
+	}
+	mx, _ := lt.Max()
+	if kl, _ := lt.KthLargest(1); kl != mx {
+		t.Logf("Expected KthLargest(1) to equal Max, saw %v vs %v", kl, mx)
+		t.Fail()
+	}
+
+	for _, k := range []uint{0, 7} {
+		if _, ok := lt.KthSmallest(k); ok {
+			t.Logf("Expected KthSmallest(%d) to be out of range", k)
+			t.Fail()
+		}
+		if _, ok := lt.KthLargest(k); ok {
+			t.Logf("Expected KthLargest(%d) to be out of range", k)
+			t.Fail()
+		}
+	}
+	if _, ok := gerbst.NewLockingTree().Max(); ok {
+		t.Log("Expected Max on empty tree to return false")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_NilReceiver(t *testing.T) {
+	var lt *gerbst.LockingTree
+
+	t.Run("counts", testutil.BuildTestCounts(lt, false, 0, 0, 0))
+	t.Run("depths", testutil.BuildTestDepths(lt, false, 0, 0, 0))
+	t.Run("gets", testutil.BuildTestGets(lt, false, testutil.GetTestsFromKeys(nil, []uint{0, 1})))
+
+	if lt.Contains(1) || !lt.ContainsAll(nil) || lt.ContainsAny([]uint{1}) {
+		t.Log("Expected nil tree to contain nothing")
+		t.Fail()
+	}
+	if lo, hi := lt.LowestKey(), lt.HighestKey(); lo != 0 || hi != 0 {
+		t.Logf("Expected nil tree key bounds to be 0, saw %d and %d", lo, hi)
+		t.Fail()
+	}
+	if keys := lt.Keys(); len(keys) != 0 {
+		t.Logf("Expected nil tree to have no keys, saw %v", keys)
+		t.Fail()
+	}
+	if st := lt.StringTree(); st != "" {
+		t.Logf("Expected nil tree to print as empty, saw %q", st)
+		t.Fail()
+	}
+	if _, ok := lt.Iterator().Next(); ok {
+		t.Log("Expected iterator over nil tree to be exhausted")
+		t.Fail()
+	}
+	if !lt.EqualSet(gerbst.NewLockingTree()) {
+		t.Log("Expected nil tree to be set-equal to an empty tree")
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected nil tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Log("Expected Put on nil tree to panic")
+			t.Fail()
+		}
+	}()
+	lt.Put(1, 1)
+}
+
+func TestLockingTree_DepthBase(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+
+	tests := []struct {
+		name  string
+		opts  []gerbst.LockingTreeOption
+		depth map[uint]uint
+		max   [3]uint
+	}{
+		{
+			name:  "default",
+			depth: map[uint]uint{12: 1, 11: 2, 90: 2, 7: 3, 82: 3, 9: 4},
+			max:   [3]uint{4, 4, 3},
+		},
+		{
+			name:  "one",
+			opts:  []gerbst.LockingTreeOption{gerbst.WithDepthBase(gerbst.DepthBaseOne)},
+			depth: map[uint]uint{12: 1, 11: 2, 90: 2, 7: 3, 82: 3, 9: 4},
+			max:   [3]uint{4, 4, 3},
+		},
+		{
+			name:  "zero",
+			opts:  []gerbst.LockingTreeOption{gerbst.WithDepthBase(gerbst.DepthBaseZero)},
+			depth: map[uint]uint{12: 0, 11: 1, 90: 1, 7: 2, 82: 2, 9: 3},
+			max:   [3]uint{3, 3, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lt := gerbst.NewLockingTreeWithKeys(keys, tt.opts...)
+			t.Run("depths", testutil.BuildTestDepths(lt, false, tt.max[0], tt.max[1], tt.max[2]))
+
+			for k, d := range tt.depth {
+				if ld, ok := lt.Depth(k); !ok || ld != d {
+					t.Logf("Expected Depth(%d) to be %d, saw %d (%t)", k, d, ld, ok)
+					t.Fail()
+				}
+				if n, _ := lt.Get(k); n.Depth() != d {
+					t.Logf("Expected node %d to report depth %d, saw %d", k, d, n.Depth())
+					t.Fail()
+				}
+			}
+			if h := lt.Height(); h != 4 {
+				t.Logf("Expected Height to be 4 regardless of base, saw %d", h)
+				t.Fail()
+			}
+			if err := lt.SelfCheck(); err != nil {
+				t.Logf("Expected tree to pass self check, saw %v", err)
+				t.Fail()
+			}
+		})
+	}
+
+	if _, ok := gerbst.NewLockingTree().Depth(1); ok {
+		t.Log("Expected Depth on empty tree to return false")
+		t.Fail()
+	}
+	if h := gerbst.NewLockingTree().Height(); h != 0 {
+		t.Logf("Expected empty tree to have Height 0, saw %d", h)
+		t.Fail()
+	}
+}
+
+func TestNewBalancedTreeWithSortedKeys(t *testing.T) {
+	lt := gerbst.NewBalancedTreeWithSortedKeys([]uint{1, 2, 3, 4, 5, 6, 7})
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected balanced tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+	if d, _ := lt.Depth(4); d != 1 {
+		t.Logf("Expected balanced tree to be rooted at the median key 4, saw it at depth %d", d)
+		t.Fail()
+	}
+	if h := lt.Height(); h != 3 {
+		t.Logf("Expected balanced tree of 7 keys to have height 3, saw %d", h)
+		t.Fail()
+	}
+	if c := gerbst.NewBalancedTreeWithSortedKeys(nil).Count(); c != 0 {
+		t.Logf("Expected tree built from no keys to be empty, saw %d", c)
+		t.Fail()
+	}
+
+	limited := gerbst.NewBalancedTreeWithSortedKeys([]uint{1, 2, 3, 4, 5}, gerbst.WithInsertLimit(2))
+	if s := fmt.Sprint(limited.Keys()); s != "[1 2]" {
+		t.Logf("Expected keys beyond the insert limit to be dropped, saw %s", s)
+		t.Fail()
+	}
+	if _, err := gerbst.NewBalancedTreeWithSortedKeysChecked([]uint{1, 2, 3}, gerbst.WithInsertLimit(2)); !errors.Is(err, gerbst.ErrTreeFull) {
+		t.Logf("Expected ErrTreeFull building more keys than the insert limit, saw %v", err)
+		t.Fail()
+	}
+
+	indexed := gerbst.NewBalancedTreeWithSortedKeys([]uint{1, 2, 3}, gerbst.WithValueIndex())
+	if keys := indexed.KeysForValue(uint(2)); len(keys) != 1 || keys[0] != 2 {
+		t.Logf("Expected value index to hold key 2, saw %v", keys)
+		t.Fail()
+	}
+}
+
+func TestNewBalancedTreeWithSortedKeysChecked(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []uint
+		ok   bool
+	}{
+		{name: "sorted", keys: []uint{1, 2, 3}, ok: true},
+		{name: "empty", keys: nil, ok: true},
+		{name: "unsorted", keys: []uint{3, 1, 2}},
+		{name: "duplicate", keys: []uint{1, 2, 2, 3}},
+		{name: "descent", keys: []uint{1, 2, 4, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lt, err := gerbst.NewBalancedTreeWithSortedKeysChecked(tt.keys)
+			if tt.ok {
+				if err != nil {
+					t.Logf("Expected keys %v to be accepted, saw %v", tt.keys, err)
+					t.Fail()
+				} else if c := lt.Count(); c != uint(len(tt.keys)) {
+					t.Logf("Expected tree to hold %d keys, saw %d", len(tt.keys), c)
+					t.Fail()
+				}
+				return
+			}
+			if !errors.Is(err, gerbst.ErrUnsortedKeys) {
+				t.Logf("Expected keys %v to be rejected with ErrUnsortedKeys, saw %v", tt.keys, err)
+				t.Fail()
+			}
+			if lt != nil {
+				t.Log("Expected no tree to be returned on error")
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestLockingTree_NegativeCache(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}, gerbst.WithNegativeCache(2))
+
+	for i := 0; i < 2; i++ {
+		if _, ok := lt.Get(50); ok {
+			t.Log("Expected absent key 50 not to be found")
+			t.Fail()
+		}
+	}
+
+	lt.Put(50, "fifty")
+	if n, ok := lt.Get(50); !ok {
+		t.Log("Expected key 50 to be found once put after a cached miss")
+		t.Fail()
+	} else if v := n.Value(); v != "fifty" {
+		t.Logf("Expected key 50 to hold value \"fifty\", saw %v", v)
+		t.Fail()
+	}
+
+	// overflow the cache, then confirm keys beyond the oldest are still invalidated on put
+	for _, k := range []uint{20, 30, 40} {
+		lt.Get(k)
+	}
+	for _, k := range []uint{20, 30, 40} {
+		lt.Put(k, k)
+		if _, ok := lt.Get(k); !ok {
+			t.Logf("Expected key %d to be found once put", k)
+			t.Fail()
+		}
+	}
+
+	lt.Delete(50)
+	if _, ok := lt.Get(50); ok {
+		t.Log("Expected deleted key 50 not to be found")
+		t.Fail()
+	}
+	lt.Put(50, "again")
+	if _, ok := lt.Get(50); !ok {
+		t.Log("Expected key 50 to be found once put again")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_DeepestNode(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []uint
+		key   uint
+		depth uint
+	}{
+		{name: "single", keys: []uint{5}, key: 5, depth: 1},
+		{name: "sample", keys: []uint{12, 11, 90, 82, 7, 9}, key: 9, depth: 4},
+		{name: "tie", keys: []uint{10, 5, 15, 3, 20}, key: 3, depth: 3},
+		{name: "right", keys: []uint{10, 5, 15, 20, 30}, key: 30, depth: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, ok := gerbst.NewLockingTreeWithKeys(tt.keys).DeepestNode()
+			if !ok {
+				t.Log("Expected deepest node to be found")
+				t.FailNow()
+			}
+			if n.Key() != tt.key || n.Depth() != tt.depth {
+				t.Logf("Expected deepest node %d at depth %d, saw %d at depth %d", tt.key, tt.depth, n.Key(), n.Depth())
+				t.Fail()
+			}
+		})
+	}
+
+	if _, ok := gerbst.NewLockingTree().DeepestNode(); ok {
+		t.Log("Expected empty tree to have no deepest node")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_GetWithRank(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	for _, k := range []uint{7, 9, 11, 12, 82, 90} {
+		rank, _ := lt.Rank(k)
+		n, r, ok := lt.GetWithRank(k)
+		if !ok {
+			t.Logf("Expected key %d to be found", k)
+			t.Fail()
+			continue
+		}
+		if n.Key() != k {
+			t.Logf("Expected node with key %d, saw %d", k, n.Key())
+			t.Fail()
+		}
+		if r != rank {
+			t.Logf("Expected key %d to have rank %d, saw %d", k, rank, r)
+			t.Fail()
+		}
+	}
+	if n, r, ok := lt.GetWithRank(50); ok || n != nil || r != 0 {
+		t.Logf("Expected absent key to return nothing, saw %v, %d, %t", n, r, ok)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_StringTreeWithValueFunc(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	lt := gerbst.NewLockingTree()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		lt.Put(k, point{X: int(k), Y: -int(k)})
+	}
+
+	vf := func(v interface{}) string {
+		p := v.(point)
+		return fmt.Sprintf("%d,%d", p.X, p.Y)
+	}
+	custom := lt.StringTreeWithValueFunc(vf)
+	def := lt.StringTree()
+
+	customLines := strings.Split(custom, "\n")
+	defLines := strings.Split(def, "\n")
+	if len(customLines) != len(defLines) {
+		t.Logf("Expected %d lines, saw %d", len(defLines), len(customLines))
+		t.FailNow()
+	}
+	for i := range defLines {
+		// strip everything from the value onward, leaving the branches, side, and key
+		dp, cp := defLines[i], customLines[i]
+		if j := strings.Index(dp, "("); j >= 0 {
+			dp = dp[:j]
+		}
+		if j := strings.Index(cp, "("); j >= 0 {
+			cp = cp[:j]
+		}
+		if dp != cp {
+			t.Logf("Expected line %d frame %q, saw %q", i, dp, cp)
+			t.Fail()
+		}
+	}
+	if !strings.Contains(custom, "ROOT[12(12,-12)]") {
+		t.Logf("Expected custom value text for the root, saw:\n%s", custom)
+		t.Fail()
+	}
+	if strings.Contains(custom, "{") {
+		t.Logf("Expected no default struct formatting, saw:\n%s", custom)
+		t.Fail()
+	}
+
+	if s := lt.StringTreeWithValueFunc(nil); s != def {
+		t.Logf("Expected nil value func to match StringTree, saw:\n%s", s)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_DeleteMinMax(t *testing.T) {
+	sorted := []uint{7, 9, 11, 12, 82, 90}
+
+	t.Run("min", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+		for _, expected := range sorted {
+			n, ok := lt.DeleteMin()
+			if !ok || n.Key() != expected {
+				t.Logf("Expected DeleteMin to remove key %d, saw %v (%t)", expected, n, ok)
+				t.FailNow()
+			}
+			if err := lt.SelfCheck(); err != nil {
+				t.Logf("Expected tree to pass self check after removing key %d, saw %v", expected, err)
+				t.Fail()
+			}
+		}
+		if c := lt.Count(); c != 0 {
+			t.Logf("Expected tree to be empty, saw count %d", c)
+			t.Fail()
+		}
+		if _, ok := lt.DeleteMin(); ok {
+			t.Log("Expected DeleteMin on empty tree to return false")
+			t.Fail()
+		}
+	})
+
+	t.Run("max", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+		for i := len(sorted) - 1; i >= 0; i-- {
+			n, ok := lt.DeleteMax()
+			if !ok || n.Key() != sorted[i] {
+				t.Logf("Expected DeleteMax to remove key %d, saw %v (%t)", sorted[i], n, ok)
+				t.FailNow()
+			}
+			if err := lt.SelfCheck(); err != nil {
+				t.Logf("Expected tree to pass self check after removing key %d, saw %v", sorted[i], err)
+				t.Fail()
+			}
+		}
+		if _, ok := lt.DeleteMax(); ok {
+			t.Log("Expected DeleteMax on empty tree to return false")
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_IsAncestor(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := []struct {
+		ancestor, descendant uint
+		expected             bool
+	}{
+		{11, 9, true},
+		{12, 9, true},
+		{7, 9, true},
+		{90, 9, false},
+		{9, 11, false},
+		{12, 82, true},
+		{11, 82, false},
+		{9, 9, false},
+	}
+	for _, tt := range tests {
+		is, err := lt.IsAncestor(tt.ancestor, tt.descendant)
+		if err != nil {
+			t.Logf("Expected IsAncestor(%d, %d) not to error, saw %v", tt.ancestor, tt.descendant, err)
+			t.Fail()
+		} else if is != tt.expected {
+			t.Logf("Expected IsAncestor(%d, %d) to be %t, saw %t", tt.ancestor, tt.descendant, tt.expected, is)
+			t.Fail()
+		}
+	}
+
+	for _, pair := range [][2]uint{{50, 9}, {11, 50}, {50, 50}} {
+		if _, err := lt.IsAncestor(pair[0], pair[1]); !errors.Is(err, gerbst.ErrKeyNotFound) {
+			t.Logf("Expected IsAncestor(%d, %d) to return ErrKeyNotFound, saw %v", pair[0], pair[1], err)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_DivergencePoint(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := []struct {
+		a, b     uint
+		expected uint
+	}{
+		{a: 8, b: 100, expected: 12},
+		{a: 100, b: 8, expected: 12},
+		{a: 8, b: 10, expected: 9},
+		{a: 7, b: 9, expected: 7},
+		{a: 83, b: 89, expected: 82},
+		{a: 5, b: 5, expected: 7},
+		{a: 12, b: 1, expected: 12},
+	}
+	for _, tt := range tests {
+		if n, ok := lt.DivergencePoint(tt.a, tt.b); !ok || n.Key() != tt.expected {
+			t.Logf("Expected paths for %d and %d to diverge at %d, saw %v", tt.a, tt.b, tt.expected, n)
+			t.Fail()
+		}
+	}
+
+	if _, ok := gerbst.NewLockingTree().DivergencePoint(1, 2); ok {
+		t.Log("Expected no divergence point in an empty tree")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Page(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := []struct {
+		offset, limit uint
+		expected      []uint
+	}{
+		{2, 2, []uint{11, 12}},
+		{0, 3, []uint{7, 9, 11}},
+		{4, 10, []uint{82, 90}},
+		{0, 6, []uint{7, 9, 11, 12, 82, 90}},
+		{5, 1, []uint{90}},
+		{6, 1, nil},
+		{1, 0, nil},
+	}
+	for _, tt := range tests {
+		page := lt.Page(tt.offset, tt.limit)
+		if len(page) != len(tt.expected) {
+			t.Logf("Expected Page(%d, %d) to return %d nodes, saw %d", tt.offset, tt.limit, len(tt.expected), len(page))
+			t.Fail()
+			continue
+		}
+		for i, n := range page {
+			if n.Key() != tt.expected[i] {
+				t.Logf("Expected Page(%d, %d)[%d] to be key %d, saw %d", tt.offset, tt.limit, i, tt.expected[i], n.Key())
+				t.Fail()
+			}
+		}
+	}
+
+	if page := gerbst.NewLockingTree().Page(0, 5); len(page) != 0 {
+		t.Logf("Expected empty tree to return an empty page, saw %d nodes", len(page))
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Entries(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	lt.Put(82, "eighty-two")
+
+	expected := []gerbst.Entry{
+		{Key: 7, Value: uint(7)},
+		{Key: 9, Value: uint(9)},
+		{Key: 11, Value: uint(11)},
+		{Key: 12, Value: uint(12)},
+		{Key: 82, Value: "eighty-two"},
+		{Key: 90, Value: uint(90)},
+	}
+	entries := lt.Entries()
+	if len(entries) != len(expected) {
+		t.Logf("Expected %d entries, saw %d", len(expected), len(entries))
+		t.FailNow()
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Logf("Expected entry %d to be %+v, saw %+v", i, expected[i], entries[i])
+			t.Fail()
+		}
+	}
+	if c := lt.Count(); c != 6 {
+		t.Logf("Expected Entries to leave the tree intact, saw count %d", c)
+		t.Fail()
+	}
+
+	if e := gerbst.NewLockingTree().Entries(); e == nil || len(e) != 0 {
+		t.Logf("Expected empty tree to return an empty slice, saw %v", e)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Drain(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeysValue([]uint{12, 11, 90, 82, 7, 9}, "v")
+
+	pairs := lt.Drain()
+	sorted := []uint{7, 9, 11, 12, 82, 90}
+	if len(pairs) != len(sorted) {
+		t.Logf("Expected %d pairs, saw %d", len(sorted), len(pairs))
+		t.FailNow()
+	}
+	for i, p := range pairs {
+		if p.Key != sorted[i] || p.Value != "v" {
+			t.Logf("Expected pair %d to be %d:v, saw %d:%v", i, sorted[i], p.Key, p.Value)
+			t.Fail()
+		}
+	}
+	if c := lt.Count(); c != 0 {
+		t.Logf("Expected tree to be empty after drain, saw count %d", c)
+		t.Fail()
+	}
+	if _, ok := lt.Get(12); ok {
+		t.Log("Expected drained key not to be found")
+		t.Fail()
+	}
+	if pairs := lt.Drain(); len(pairs) != 0 {
+		t.Logf("Expected draining an empty tree to return no pairs, saw %d", len(pairs))
+		t.Fail()
+	}
+
+	lt.Put(5, nil)
+	if c := lt.Count(); c != 1 {
+		t.Logf("Expected drained tree to remain usable, saw count %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Rebalance(t *testing.T) {
+	lt := gerbst.NewLockingTree()
+	for i := uint(1); i <= 100; i++ {
+		lt.Put(i, i)
+	}
+	if h := lt.Height(); h != 100 {
+		t.Logf("Expected ascending inserts to produce height 100, saw %d", h)
+		t.Fail()
+	}
+	lt.Rebalance()
+	if h := lt.Height(); h != 7 {
+		t.Logf("Expected rebalanced tree of 100 keys to have height 7, saw %d", h)
+		t.Fail()
+	}
+	if c := lt.Count(); c != 100 {
+		t.Logf("Expected rebalance to preserve 100 keys, saw %d", c)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected rebalanced tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_BalanceDSW(t *testing.T) {
+	for _, ascending := range []bool{true, false} {
+		lt := gerbst.NewLockingTree()
+		for i := uint(1); i <= 100; i++ {
+			k := i
+			if !ascending {
+				k = 101 - i
+			}
+			lt.Put(k, k)
+		}
+		ids := make(map[uint]uint64, 100)
+		lt.InOrderIndexed(func(_ uint, n *gerbst.Node) bool {
+			ids[n.Key()] = n.ID()
+			return true
+		})
+
+		lt.BalanceDSW()
+		if !lt.IsBalanced() {
+			t.Logf("Expected degenerate tree (ascending: %t) to be balanced", ascending)
+			t.Fail()
+		}
+		if h := lt.Height(); h != 7 {
+			t.Logf("Expected balanced tree of 100 keys to have height 7, saw %d", h)
+			t.Fail()
+		}
+		if err := lt.SelfCheck(); err != nil {
+			t.Logf("Expected balanced tree to pass self check, saw %v", err)
+			t.Fail()
+		}
+		lt.InOrderIndexed(func(i uint, n *gerbst.Node) bool {
+			if n.Key() != i+1 || n.Value() != i+1 || n.ID() != ids[n.Key()] {
+				t.Logf("Expected key %d to keep its value and ID, saw %v with ID %d", i+1, n, n.ID())
+				t.Fail()
+			}
+			return true
+		})
+		if r := lt.RotationsToBalance(); r != 0 {
+			t.Logf("Expected balanced tree to need no rotations, saw %d", r)
+			t.Fail()
+		}
+	}
+
+	// a balanced tree is left exactly as it is
+	lt := gerbst.NewLockingTreeWithKeys([]uint{4, 2, 6, 1})
+	before := lt.StringTree()
+	lt.BalanceDSW()
+	if after := lt.StringTree(); after != before {
+		t.Logf("Expected balanced tree to be untouched, saw:\n%s", after)
+		t.Fail()
+	}
+
+	gerbst.NewLockingTree().BalanceDSW()
+}
+
+func TestLockingTree_AutoRebalance(t *testing.T) {
+	const (
+		count  = 1000
+		factor = 2.0
+	)
+	lt := gerbst.NewLockingTree(gerbst.WithAutoRebalance(factor))
+	for i := uint(1); i <= count; i++ {
+		lt.Put(i, i)
+		if c, h := lt.Count(), lt.Height(); c > 2 && float64(h) > factor*math.Log2(float64(c)) {
+			t.Logf("Expected height to stay within %.1f*log2(%d), saw %d", factor, c, h)
+			t.FailNow()
+		}
+	}
+	if h := lt.Height(); h > 20 {
+		t.Logf("Expected height of %d ascending keys to remain bounded, saw %d", count, h)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected auto-rebalanced tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+	for _, k := range []uint{1, 500, 1000} {
+		if n, ok := lt.Get(k); !ok || n.Value() != k {
+			t.Logf("Expected key %d to hold its own value, saw %v (%t)", k, n, ok)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_InBounds(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	for k, expected := range map[uint]bool{0: false, 6: false, 7: true, 8: true, 50: true, 90: true, 91: false} {
+		if in := lt.InBounds(k); in != expected {
+			t.Logf("Expected InBounds(%d) to be %t, saw %t", k, expected, in)
+			t.Fail()
+		}
+	}
+	if gerbst.NewLockingTree().InBounds(0) {
+		t.Log("Expected no key to be in bounds of an empty tree")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_ValueIndex(t *testing.T) {
+	check := func(t *testing.T, lt *gerbst.LockingTree, value interface{}, expected []uint) {
+		t.Helper()
+		if keys := lt.KeysForValue(value); fmt.Sprint(keys) != fmt.Sprint(expected) {
+			t.Logf("Expected value %v to be held by keys %v, saw %v", value, expected, keys)
+			t.Fail()
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []gerbst.LockingTreeOption
+	}{{name: "indexed", opts: []gerbst.LockingTreeOption{gerbst.WithValueIndex()}}, {name: "scan"}} {
+		t.Run(tc.name, func(t *testing.T) {
+			lt := gerbst.NewLockingTree(tc.opts...)
+			for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+				lt.Put(k, k%2 == 0)
+			}
+			check(t, lt, true, []uint{12, 82, 90})
+			check(t, lt, false, []uint{7, 9, 11})
+
+			t.Run("value_change", func(t *testing.T) {
+				lt.Put(82, false)
+				check(t, lt, true, []uint{12, 90})
+				check(t, lt, false, []uint{7, 9, 11, 82})
+				lt.Put(82, "other")
+				check(t, lt, false, []uint{7, 9, 11})
+				check(t, lt, "other", []uint{82})
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				lt.Delete(82)
+				check(t, lt, "other", nil)
+				lt.DeleteMin()
+				check(t, lt, false, []uint{9, 11})
+			})
+
+			t.Run("map_values", func(t *testing.T) {
+				lt.MapValues(func(key uint, _ interface{}) interface{} { return key > 10 })
+				check(t, lt, true, []uint{11, 12, 90})
+				check(t, lt, false, []uint{9})
+			})
+
+			t.Run("drain", func(t *testing.T) {
+				lt.Drain()
+				check(t, lt, true, nil)
+				lt.Put(1, true)
+				check(t, lt, true, []uint{1})
+			})
+		})
+	}
+
+	t.Run("evict", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeysValue([]uint{12, 11, 90}, "v", gerbst.WithValueIndex())
+		lt.SetMaxCount(2)
+		check(t, lt, "v", []uint{12, 90})
+	})
+}
+
+func TestLockingTree_Rotate(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	original := lt.StringTree()
+
+	if err := lt.RotateLeft(12); err != nil {
+		t.Logf("Expected left rotation around the root to succeed, saw %v", err)
+		t.FailNow()
+	}
+	if d, _ := lt.Depth(90); d != 1 {
+		t.Logf("Expected key 90 to become the root, saw it at depth %d", d)
+		t.Fail()
+	}
+	if d, _ := lt.Depth(9); d != 5 {
+		t.Logf("Expected key 9 to sink to depth 5, saw %d", d)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected rotated tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+
+	if err := lt.RotateRight(90); err != nil {
+		t.Logf("Expected right rotation around the new root to succeed, saw %v", err)
+		t.FailNow()
+	}
+	if s := lt.StringTree(); s != original {
+		t.Logf("Expected left then right rotation to restore the original structure\nexpected:\n%s\nsaw:\n%s", original, s)
+		t.Fail()
+	}
+
+	if err := lt.RotateRight(11); err != nil {
+		t.Logf("Expected right rotation around an inner node to succeed, saw %v", err)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected rotated tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+
+	if err := lt.RotateLeft(90); !errors.Is(err, gerbst.ErrMissingChild) {
+		t.Logf("Expected rotating key 90 left to return ErrMissingChild, saw %v", err)
+		t.Fail()
+	}
+	if err := lt.RotateRight(50); !errors.Is(err, gerbst.ErrKeyNotFound) {
+		t.Logf("Expected rotating an absent key to return ErrKeyNotFound, saw %v", err)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_ComparisonCounter(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}, gerbst.WithComparisonCounter())
+	// each insert compares against every node above the new node
+	if c := lt.ComparisonCount(); c != 0+1+1+2+2+3 {
+		t.Logf("Expected 9 comparisons from building the sample tree, saw %d", c)
+		t.Fail()
+	}
+
+	lt.ResetComparisonCount()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		before := lt.ComparisonCount()
+		lt.Get(k)
+		depth, _ := lt.Depth(k)
+		if c := lt.ComparisonCount() - before; c != depth {
+			t.Logf("Expected Get(%d) to perform %d comparisons, saw %d", k, depth, c)
+			t.Fail()
+		}
+	}
+	if c := lt.ComparisonCount(); c != 1+2+2+3+3+4 {
+		t.Logf("Expected 15 comparisons across all lookups, saw %d", c)
+		t.Fail()
+	}
+
+	lt.ResetComparisonCount()
+	lt.GetRecurse(10)
+	if c := lt.ComparisonCount(); c != 4 {
+		t.Logf("Expected a miss beneath key 9 to perform 4 comparisons, saw %d", c)
+		t.Fail()
+	}
+	lt.Get(1000)
+	if c := lt.ComparisonCount(); c != 4 {
+		t.Logf("Expected an out of bounds key to perform no comparisons, saw %d total", c)
+		t.Fail()
+	}
+
+	if c := gerbst.NewLockingTreeWithKeys([]uint{1, 2, 3}).ComparisonCount(); c != 0 {
+		t.Logf("Expected no comparisons to be counted without the option, saw %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Increment(t *testing.T) {
+	const (
+		workers    = 16
+		increments = 500
+	)
+	lt := gerbst.NewLockingTree()
+	done := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < increments; i++ {
+				if _, err := lt.Increment(uint(i%5), uint(w%3+1)); err != nil {
+					t.Logf("Expected increment to succeed, saw %v", err)
+					t.Fail()
+					return
+				}
+			}
+		}(w)
+	}
+	for w := 0; w < workers; w++ {
+		<-done
+	}
+
+	var expected uint
+	for w := 0; w < workers; w++ {
+		expected += uint(w%3+1) * increments
+	}
+	if sum, err := lt.SumUintValues(); err != nil || sum != expected {
+		t.Logf("Expected concurrent increments to sum to %d, saw %d (%v)", expected, sum, err)
+		t.Fail()
+	}
+	if c := lt.Count(); c != 5 {
+		t.Logf("Expected 5 counters, saw %d", c)
+		t.Fail()
+	}
+
+	if total, err := lt.Increment(100, 7); err != nil || total != 7 {
+		t.Logf("Expected incrementing an absent key to insert it with the delta 7, saw %d (%v)", total, err)
+		t.Fail()
+	}
+	if total, err := lt.Increment(100, 3); err != nil || total != 10 {
+		t.Logf("Expected incremented total of 10, saw %d (%v)", total, err)
+		t.Fail()
+	}
+	if c := lt.DuplicateCount(); c != 0 {
+		t.Logf("Expected incrementing existing keys not to count as duplicates, saw %d", c)
+		t.Fail()
+	}
+
+	lt.Put(200, "not a uint")
+	if _, err := lt.Increment(200, 1); err == nil {
+		t.Log("Expected incrementing a non-uint value to error")
+		t.Fail()
+	}
+	if n, _ := lt.Get(200); n.Value() != "not a uint" {
+		t.Logf("Expected failed increment to leave the value untouched, saw %v", n.Value())
+		t.Fail()
+	}
+
+	t.Run("collision_resolver", func(t *testing.T) {
+		// keep the existing value whenever it is at least 5
+		rlt := gerbst.NewLockingTree(gerbst.WithCollisionResolver(func(old, new interface{}) interface{} {
+			if old.(uint) >= 5 {
+				return old
+			}
+			return new
+		}))
+		rlt.Put(1, uint(5))
+		if total, err := rlt.Increment(1, 3); err != nil || total != 5 {
+			t.Logf("Expected Increment to return the value kept by the resolver, 5, saw %d (%v)", total, err)
+			t.Fail()
+		}
+		if n, _ := rlt.Get(1); n.Value() != uint(5) {
+			t.Logf("Expected the resolver to keep 5, saw %v", n.Value())
+			t.Fail()
+		}
+
+		slt := gerbst.NewLockingTree(gerbst.WithCollisionResolver(func(old, new interface{}) interface{} { return "reset" }))
+		slt.Put(1, uint(5))
+		if _, err := slt.Increment(1, 3); err == nil {
+			t.Log("Expected error when the resolver stores a value that is not a uint")
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_Range(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	tests := []struct {
+		lo, hi   uint
+		expected string
+	}{
+		{0, 100, "[7 9 11 12 82 90]"},
+		{9, 12, "[9 11 12]"},
+		{8, 8, "[]"},
+		{82, 82, "[82]"},
+		{91, 200, "[]"},
+		{12, 9, "[]"},
+	}
+	for _, tt := range tests {
+		keys := make([]uint, 0)
+		for _, n := range lt.Range(tt.lo, tt.hi) {
+			keys = append(keys, n.Key())
+		}
+		if s := fmt.Sprint(keys); s != tt.expected {
+			t.Logf("Expected Range(%d, %d) to return %s, saw %s", tt.lo, tt.hi, tt.expected, s)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_RemoveRange(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9, 50, 10, 95})
+
+	expected := lt.Range(9, 50)
+	removed := lt.RemoveRange(9, 50)
+	if len(removed) != len(expected) {
+		t.Logf("Expected %d removed nodes, saw %d", len(expected), len(removed))
+		t.FailNow()
+	}
+	for i := range expected {
+		if removed[i] != expected[i] {
+			t.Logf("Expected removed node %d to be %v, saw %v", i, expected[i], removed[i])
+			t.Fail()
+		}
+		if _, ok := lt.Get(expected[i].Key()); ok {
+			t.Logf("Expected key %d to be removed", expected[i].Key())
+			t.Fail()
+		}
+	}
+	if c := lt.Count(); c != 4 {
+		t.Logf("Expected 4 keys to remain, saw %d", c)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected tree to pass self check after range removal, saw %v", err)
+		t.Fail()
+	}
+	if r := lt.RemoveRange(13, 81); len(r) != 0 {
+		t.Logf("Expected removing an empty range to remove nothing, saw %d", len(r))
+		t.Fail()
+	}
+}
+
+func TestLockingTree_DeleteFunc(t *testing.T) {
+	keys := make([]uint, 0, 64)
+	for _, k := range rand.New(rand.NewSource(195)).Perm(64) {
+		keys = append(keys, uint(k))
+	}
+	lt := gerbst.NewLockingTreeWithKeys(keys, gerbst.WithValueIndex())
+
+	var calls uint
+	removed := lt.DeleteFunc(func(key uint, value interface{}) bool {
+		calls++
+		return value.(uint)%2 == 0
+	})
+	if removed != 32 || calls != 64 {
+		t.Logf("Expected 32 of 64 keys removed with one call each, saw %d removed after %d calls", removed, calls)
+		t.Fail()
+	}
+	for _, k := range keys {
+		if _, ok := lt.Get(k); ok == (k%2 == 0) {
+			t.Logf("Expected key %d presence to be %t", k, k%2 != 0)
+			t.Fail()
+		}
+	}
+	if c := lt.Count(); c != 32 {
+		t.Logf("Expected 32 keys to remain, saw %d", c)
+		t.Fail()
+	}
+	if k := lt.KeysForValue(uint(4)); len(k) != 0 {
+		t.Logf("Expected removed keys to be dropped from the value index, saw %v", k)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected tree to pass self check after deletion, saw %v", err)
+		t.Fail()
+	}
+	if !lt.IsBalanced() {
+		t.Logf("Expected survivors to be rebuilt height-balanced, saw:\n%s", lt.StringTree())
+		t.Fail()
+	}
+
+	shape := lt.StringTree()
+	if r := lt.DeleteFunc(func(uint, interface{}) bool { return false }); r != 0 || lt.Count() != 32 {
+		t.Logf("Expected a predicate matching nothing to remove nothing, saw %d", r)
+		t.Fail()
+	}
+	if s := lt.StringTree(); s != shape {
+		t.Logf("Expected a predicate matching nothing to leave the shape untouched\nexpected:\n%s\nsaw:\n%s", shape, s)
+		t.Fail()
+	}
+	if r := lt.DeleteFunc(func(uint, interface{}) bool { return true }); r != 32 || lt.Count() != 0 {
+		t.Logf("Expected a predicate matching everything to empty the tree, saw %d removed and %d left", r, lt.Count())
+		t.Fail()
+	}
+	if r := lt.DeleteFunc(func(uint, interface{}) bool { return true }); r != 0 {
+		t.Logf("Expected nothing to be removed from an empty tree, saw %d", r)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_KeyNormalizer(t *testing.T) {
+	bucket := func(k uint) uint { return k &^ 0xF }
+	lt := gerbst.NewLockingTree(gerbst.WithKeyNormalizer(bucket))
+
+	lt.Put(0x21, "a")
+	lt.Put(0x2E, "b")
+	if c := lt.Count(); c != 1 {
+		t.Logf("Expected keys in the same bucket to share one node, saw %d nodes", c)
+		t.Fail()
+	}
+	if c := lt.DuplicateCount(); c != 1 {
+		t.Logf("Expected the second put to count as a duplicate, saw %d", c)
+		t.Fail()
+	}
+	for _, k := range []uint{0x20, 0x21, 0x2F} {
+		if n, ok := lt.Get(k); !ok || n.Key() != 0x20 || n.Value() != "b" {
+			t.Logf("Expected Get(%#x) to find bucket 0x20 holding \"b\", saw %v (%t)", k, n, ok)
+			t.Fail()
+		}
+		if !lt.Contains(k) {
+			t.Logf("Expected Contains(%#x) to be true", k)
+			t.Fail()
+		}
+	}
+	if _, ok := lt.Get(0x30); ok {
+		t.Log("Expected key in another bucket not to be found")
+		t.Fail()
+	}
+
+	lt.Put(0x35, "c")
+	if total, err := lt.Increment(0x47, 2); err != nil || total != 2 {
+		t.Logf("Expected increment of a new bucket to return 2, saw %d (%v)", total, err)
+		t.Fail()
+	}
+	if total, _ := lt.Increment(0x40, 3); total != 5 {
+		t.Logf("Expected increment of the same bucket to return 5, saw %d", total)
+		t.Fail()
+	}
+	if n, ok := lt.Delete(0x3A); !ok || n.Key() != 0x30 {
+		t.Logf("Expected Delete(0x3a) to remove bucket 0x30, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+	if s := fmt.Sprint(lt.Keys()); s != fmt.Sprint([]uint{0x20, 0x40}) {
+		t.Logf("Expected buckets [32 64] to remain, saw %s", s)
+		t.Fail()
+	}
+
+	if cost, ok := lt.PathCost(0x4A); !ok || cost != 0x60 {
+		t.Logf("Expected PathCost(0x4a) to reach bucket 0x40 at cost 0x60, saw %#x (%t)", cost, ok)
+		t.Fail()
+	}
+	if parent, side, exists := lt.InsertionSide(0x2A); !exists || parent != nil || side != gerbst.NodeSideRoot {
+		t.Logf("Expected InsertionSide(0x2a) to report the existing root bucket, saw %v %s (%t)", parent, side, exists)
+		t.Fail()
+	}
+	// 0x3f normalizes to 0x30, which lies equally far from both buckets
+	if n, ok := lt.GetNearest(0x3F); !ok || n.Key() != 0x20 {
+		t.Logf("Expected GetNearest(0x3f) to find bucket 0x20, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+	if err := lt.RotateLeft(0x2A); err != nil {
+		t.Logf("Expected RotateLeft(0x2a) to rotate bucket 0x20, saw %v", err)
+		t.Fail()
+	}
+
+	bt := gerbst.NewBalancedTreeWithSortedKeys([]uint{0x05, 0x13, 0x2F}, gerbst.WithKeyNormalizer(bucket))
+	if n, ok := bt.Get(0x1A); !ok || n.Key() != 0x10 || n.Value() != uint(0x13) {
+		t.Logf("Expected balanced tree to hold bucket 0x10 with value 0x13, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+	if _, err := gerbst.NewBalancedTreeWithSortedKeysChecked([]uint{0x11, 0x1F}, gerbst.WithKeyNormalizer(bucket)); !errors.Is(err, gerbst.ErrUnsortedKeys) {
+		t.Logf("Expected keys normalizing to one bucket to be rejected with ErrUnsortedKeys, saw %v", err)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_PutWithNeighbors(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	tests := []struct {
+		key        uint
+		prev, next uint
+		noPrev     bool
+		noNext     bool
+	}{
+		{key: 50, prev: 12, next: 82},
+		{key: 10, prev: 9, next: 11},
+		{key: 11, prev: 10, next: 12},
+		{key: 1, noPrev: true, next: 7},
+		{key: 100, prev: 90, noNext: true},
+	}
+	for _, tt := range tests {
+		prev, next := lt.PutWithNeighbors(tt.key, "v")
+		if n, ok := lt.Get(tt.key); !ok || n.Value() != "v" {
+			t.Logf("Expected key %d to be put, saw %v", tt.key, n)
+			t.Fail()
+		}
+		if tt.noPrev && prev != nil || !tt.noPrev && (prev == nil || prev.Key() != tt.prev) {
+			t.Logf("Expected key %d to have predecessor %d (none: %t), saw %v", tt.key, tt.prev, tt.noPrev, prev)
+			t.Fail()
+		}
+		if tt.noNext && next != nil || !tt.noNext && (next == nil || next.Key() != tt.next) {
+			t.Logf("Expected key %d to have successor %d (none: %t), saw %v", tt.key, tt.next, tt.noNext, next)
+			t.Fail()
+		}
+	}
+
+	if prev, next := gerbst.NewLockingTree().PutWithNeighbors(5, 5); prev != nil || next != nil {
+		t.Logf("Expected lone key to have no neighbors, saw %v and %v", prev, next)
+		t.Fail()
+	}
+
+	capped := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	capped.SetMaxCount(6)
+	if prev, next := capped.PutWithNeighbors(8, 8); prev != nil || next == nil || next.Key() != 9 {
+		t.Logf("Expected eviction of key 7 to leave key 8 without a predecessor, saw %v and %v", prev, next)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_PutAt(t *testing.T) {
+	lt := gerbst.NewLockingTree()
+	steps := []struct {
+		parent uint
+		side   gerbst.NodeSide
+		key    uint
+	}{
+		{0, gerbst.NodeSideRoot, 12},
+		{12, gerbst.NodeSideRight, 90},
+		{90, gerbst.NodeSideLeft, 82},
+		{12, gerbst.NodeSideLeft, 11},
+		{11, gerbst.NodeSideLeft, 7},
+		{7, gerbst.NodeSideRight, 9},
+	}
+	for _, s := range steps {
+		if err := lt.PutAt(s.parent, s.side, s.key, s.key); err != nil {
+			t.Logf("Expected key %d to be placed %s of key %d, saw %v", s.key, s.side, s.parent, err)
+			t.FailNow()
+		}
+	}
+	if s, expected := lt.StringTree(), gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}).StringTree(); s != expected {
+		t.Logf("Expected sample tree shape\nexpected:\n%s\nsaw:\n%s", expected, s)
+		t.Fail()
+	}
+
+	rejects := []struct {
+		name   string
+		parent uint
+		side   gerbst.NodeSide
+		key    uint
+		err    error
+	}{
+		{"root_occupied", 0, gerbst.NodeSideRoot, 1, gerbst.ErrSlotOccupied},
+		{"slot_occupied", 12, gerbst.NodeSideLeft, 10, gerbst.ErrSlotOccupied},
+		{"absent_parent", 50, gerbst.NodeSideLeft, 49, gerbst.ErrKeyNotFound},
+		{"wrong_side", 9, gerbst.NodeSideLeft, 10, gerbst.ErrOrderViolation},
+		{"outside_ancestor_bounds", 9, gerbst.NodeSideRight, 20, gerbst.ErrOrderViolation},
+		{"duplicate", 82, gerbst.NodeSideRight, 90, gerbst.ErrOrderViolation},
+	}
+	for _, r := range rejects {
+		t.Run(r.name, func(t *testing.T) {
+			if err := lt.PutAt(r.parent, r.side, r.key, r.key); !errors.Is(err, r.err) {
+				t.Logf("Expected %v, saw %v", r.err, err)
+				t.Fail()
+			}
+		})
+	}
+	if c := lt.Count(); c != 6 {
+		t.Logf("Expected rejected placements to leave 6 keys, saw %d", c)
+		t.Fail()
+	}
+
+	t.Run("normalized", func(t *testing.T) {
+		nlt := gerbst.NewLockingTree(gerbst.WithKeyNormalizer(func(k uint) uint { return k / 10 * 10 }))
+		if err := nlt.PutAt(0, gerbst.NodeSideRoot, 125, 1); err != nil {
+			t.Logf("Unexpected error placing the root: %v", err)
+			t.FailNow()
+		}
+		// 128 normalizes to the root key 120, and 115 to 110, which belongs on its left
+		if err := nlt.PutAt(128, gerbst.NodeSideLeft, 115, 2); err != nil {
+			t.Logf("Expected normalized keys to be placed, saw %v", err)
+			t.FailNow()
+		}
+		if n, ok := nlt.Get(110); !ok || n.Value() != 2 || n.Side() != gerbst.NodeSideLeft {
+			t.Logf("Expected key 110 left of the root, saw %v (%t)", n, ok)
+			t.Fail()
+		}
+		if err := nlt.PutAt(120, gerbst.NodeSideRight, 121, 3); !errors.Is(err, gerbst.ErrOrderViolation) {
+			t.Logf("Expected key 121 to normalize onto the root key, saw %v", err)
+			t.Fail()
+		}
+	})
+
+	t.Run("treap", func(t *testing.T) {
+		tlt := gerbst.NewLockingTree(gerbst.WithRandomizedInsertPriority(186))
+		if err := tlt.PutAt(0, gerbst.NodeSideRoot, 12, 12); !errors.Is(err, gerbst.ErrPriorityShaped) {
+			t.Logf("Expected ErrPriorityShaped, saw %v", err)
+			t.Fail()
+		}
+		if c := tlt.Count(); c != 0 {
+			t.Logf("Expected refused placement to leave the tree empty, saw count %d", c)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_RandomizedInsertPriority(t *testing.T) {
+	const count = 10000
+	lt := gerbst.NewLockingTree(gerbst.WithRandomizedInsertPriority(186))
+	for i := uint(0); i < count; i++ {
+		lt.Put(i, i)
+	}
+
+	// the expected height of a treap is about 3*log2(n), allow some slack above that
+	if h, limit := lt.Height(), uint(4*math.Log2(count)); h > limit {
+		t.Logf("Expected ascending insertion to keep height within %d, saw %d", limit, h)
+		t.Fail()
+	}
+	if c := lt.Count(); c != count {
+		t.Logf("Expected %d keys, saw %d", count, c)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected treap to pass self check, saw %v", err)
+		t.FailNow()
+	}
+
+	for i := uint(0); i < count; i += 2 {
+		if n, ok := lt.Delete(i); !ok || n.Key() != i {
+			t.Logf("Expected key %d to be deleted, saw %v (%t)", i, n, ok)
+			t.FailNow()
+		}
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected treap to pass self check after deletions, saw %v", err)
+		t.Fail()
+	}
+	if h, limit := lt.Height(), uint(4*math.Log2(count/2)); h > limit {
+		t.Logf("Expected deletions to keep height within %d, saw %d", limit, h)
+		t.Fail()
+	}
+	if c := lt.Count(); c != count/2 {
+		t.Logf("Expected %d keys to remain, saw %d", count/2, c)
+		t.Fail()
+	}
+
+	same := gerbst.NewLockingTree(gerbst.WithRandomizedInsertPriority(186))
+	for i := uint(1); i < count; i += 2 {
+		same.Put(i, i)
+	}
+	if same.StringTree() != lt.StringTree() {
+		t.Log("Expected the same seed and keys to produce the same shape regardless of history")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Update(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90})
+	lt.Update(11, func(v interface{}, exists bool) interface{} {
+		if !exists {
+			t.Log("Expected key 11 to exist")
+			t.Fail()
+		}
+		return v.(uint) * 2
+	})
+	lt.Update(50, func(v interface{}, exists bool) interface{} {
+		if exists || v != nil {
+			t.Logf("Expected key 50 to be absent, saw %v (%t)", v, exists)
+			t.Fail()
+		}
+		return "new"
+	})
+	if n, _ := lt.Get(11); n.Value() != uint(22) {
+		t.Logf("Expected key 11 to be updated to 22, saw %v", n.Value())
+		t.Fail()
+	}
+	if n, ok := lt.Get(50); !ok || n.Value() != "new" {
+		t.Logf("Expected key 50 to be inserted, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_ReentrancyDetection(t *testing.T) {
+	// reenter runs fn in its own goroutine, returning the value it panicked with or failing if it does not return
+	reenter := func(t *testing.T, fn func()) (recovered interface{}) {
+		t.Helper()
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() { done <- recover() }()
+			fn()
+		}()
+		select {
+		case recovered = <-done:
+		case <-time.After(5 * time.Second):
+			t.Log("Expected reentrant call to be detected, but it deadlocked")
+			t.FailNow()
+		}
+		return
+	}
+
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90}, gerbst.WithReentrancyDetection())
+
+	t.Run("update", func(t *testing.T) {
+		r := reenter(t, func() {
+			lt.Update(11, func(v interface{}, _ bool) interface{} {
+				lt.Get(12)
+				return v
+			})
+		})
+		if msg, ok := r.(string); !ok || !strings.Contains(msg, "callback holding its write lock") {
+			t.Logf("Expected a descriptive panic, saw %v", r)
+			t.Fail()
+		}
+	})
+
+	t.Run("map_values", func(t *testing.T) {
+		r := reenter(t, func() {
+			lt.MapValues(func(key uint, v interface{}) interface{} {
+				lt.Put(key+1, v)
+				return v
+			})
+		})
+		if r == nil {
+			t.Log("Expected a reentrant write to be detected")
+			t.Fail()
+		}
+	})
+
+	t.Run("usable_afterward", func(t *testing.T) {
+		if r := reenter(t, func() { lt.Put(5, 5) }); r != nil {
+			t.Logf("Expected tree to remain usable after detection, saw panic %v", r)
+			t.Fail()
+		}
+		if c := lt.Count(); c != 4 {
+			t.Logf("Expected 4 keys, saw %d", c)
+			t.Fail()
+		}
+	})
+
+	t.Run("other_goroutines", func(t *testing.T) {
+		// calls made from other goroutines while a callback runs simply wait, and must not be mistaken for reentrancy
+		r := reenter(t, func() {
+			lt.Update(12, func(v interface{}, _ bool) interface{} {
+				go lt.Count()
+				return v
+			})
+		})
+		if r != nil {
+			t.Logf("Expected no detection for calls from other goroutines, saw %v", r)
+			t.Fail()
+		}
+	})
+}
+
+func TestNewLockingTreeFromMap(t *testing.T) {
+	m := make(map[uint]interface{})
+	for i := uint(0); i < 100; i++ {
+		m[i*3] = fmt.Sprintf("v%d", i)
+	}
+
+	lt := gerbst.NewLockingTreeFromMap(m)
+	if c := lt.Count(); c != uint(len(m)) {
+		t.Logf("Expected %d keys, saw %d", len(m), c)
+		t.Fail()
+	}
+	for k, v := range m {
+		if n, ok := lt.Get(k); !ok || n.Value() != v {
+			t.Logf("Expected key %d to hold %v, saw %v (%t)", k, v, n, ok)
+			t.Fail()
+		}
+	}
+	if h := lt.Height(); h != 7 {
+		t.Logf("Expected a balanced height of 7 for 100 keys, saw %d", h)
+		t.Fail()
+	}
+	if err := lt.SelfCheck(); err != nil {
+		t.Logf("Expected tree to pass self check, saw %v", err)
+		t.Fail()
+	}
+	for i := 0; i < 5; i++ {
+		if s := gerbst.NewLockingTreeFromMap(m).StringTree(); s != lt.StringTree() {
+			t.Log("Expected the same map to always produce the same shape")
+			t.Fail()
+			break
+		}
+	}
+
+	if c := gerbst.NewLockingTreeFromMap(nil).Count(); c != 0 {
+		t.Logf("Expected a nil map to produce an empty tree, saw %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_InternValues(t *testing.T) {
+	type payload struct {
+		name string
+	}
+	lt := gerbst.NewLockingTree()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		lt.Put(k, &payload{name: fmt.Sprintf("p%d", k%2)})
+	}
+
+	lt.InternValues(func(a, b interface{}) bool {
+		return a.(*payload).name == b.(*payload).name
+	})
+
+	values := make(map[string]*payload)
+	for _, k := range []uint{7, 9, 11, 12, 82, 90} {
+		n, _ := lt.Get(k)
+		p := n.Value().(*payload)
+		if shared, ok := values[p.name]; !ok {
+			values[p.name] = p
+		} else if shared != p {
+			t.Logf("Expected key %d to share the %s instance %p, saw %p", k, p.name, shared, p)
+			t.Fail()
+		}
+	}
+	if len(values) != 2 {
+		t.Logf("Expected 2 distinct values to remain, saw %d", len(values))
+		t.Fail()
+	}
+	if n, _ := lt.Get(7); n.Value().(*payload).name != "p1" {
+		t.Logf("Expected interning to preserve values, saw %v", n.Value())
+		t.Fail()
+	}
+}
+
+func TestLockingTree_NodeID(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	for _, lt := range []*gerbst.LockingTree{gerbst.NewLockingTree(), gerbst.NewLockingTreeWithCapacity(4)} {
+		for _, k := range keys {
+			lt.Put(k, k)
+		}
+
+		ids := make(map[uint]uint64, len(keys))
+		seen := make(map[uint64]bool, len(keys))
+		for _, k := range keys {
+			n, _ := lt.Get(k)
+			if seen[n.ID()] {
+				t.Logf("Expected key %d to have a unique ID, saw duplicate %d", k, n.ID())
+				t.Fail()
+			}
+			ids[k] = n.ID()
+			seen[n.ID()] = true
+		}
+
+		check := func(when string) {
+			for k, id := range ids {
+				if n, ok := lt.Get(k); !ok || n.ID() != id {
+					t.Logf("Expected key %d to keep ID %d %s, saw %v", k, id, when, n)
+					t.Fail()
+				}
+				if n, ok := lt.GetByID(id); !ok || n.Key() != k {
+					t.Logf("Expected ID %d to find key %d %s, saw %v", id, k, when, n)
+					t.Fail()
+				}
+			}
+		}
+
+		lt.Put(82, "updated")
+		check("after a value update")
+		if err := lt.RotateRight(12); err != nil {
+			t.Logf("Unexpected rotation error: %v", err)
+			t.Fail()
+		}
+		check("after rotation")
+		lt.Rebalance()
+		check("after rebalance")
+		lt.Delete(11)
+		delete(ids, 11)
+		check("after deleting a node with two children")
+
+		old := ids[9]
+		lt.Delete(9)
+		lt.Put(9, 9)
+		if n, _ := lt.Get(9); n.ID() == old {
+			t.Logf("Expected re-inserted key to receive a new ID, saw %d again", old)
+			t.Fail()
+		}
+		if _, ok := lt.GetByID(old); ok {
+			t.Logf("Expected ID %d of a deleted key to no longer be found", old)
+			t.Fail()
+		}
+	}
+
+	if _, ok := gerbst.NewLockingTree().GetByID(1); ok {
+		t.Log("Expected empty tree to find no ID")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_GetOrLoad(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	var calls int64
+	loader := func(key uint) (interface{}, bool) {
+		atomic.AddInt64(&calls, 1)
+		return key * 10, key%2 == 0
+	}
+
+	if n, ok := lt.GetOrLoad(82, loader); !ok || n.Value() != uint(82) || calls != 0 {
+		t.Logf("Expected hit to return existing node without loading, saw %v, %v after %d calls", n, ok, calls)
+		t.Fail()
+	}
+	if n, ok := lt.GetOrLoad(40, loader); !ok || n.Key() != 40 || n.Value() != uint(400) || calls != 1 {
+		t.Logf("Expected miss to load and insert key 40, saw %v, %v after %d calls", n, ok, calls)
+		t.Fail()
+	}
+	if n, ok := lt.Get(40); !ok || n.Value() != uint(400) {
+		t.Logf("Expected loaded key to be present, saw %v, %v", n, ok)
+		t.Fail()
+	}
+	if n, ok := lt.GetOrLoad(41, loader); ok || n != nil || lt.Contains(41) {
+		t.Logf("Expected declined load to insert nothing, saw %v, %v", n, ok)
+		t.Fail()
+	}
+
+	t.Run("reentrant_loader", func(t *testing.T) {
+		lt := gerbst.NewLockingTree(gerbst.WithReentrancyDetection())
+		n, ok := lt.GetOrLoad(5, func(key uint) (interface{}, bool) {
+			_, found := lt.Get(key)
+			return found, true
+		})
+		if !ok || n.Value() != false {
+			t.Logf("Expected loader to be able to read the tree, saw %v, %v", n, ok)
+			t.Fail()
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		lt := gerbst.NewLockingTree()
+		var (
+			calls int64
+			start = make(chan struct{})
+			wg    sync.WaitGroup
+			nodes = make([]*gerbst.Node, 16)
+		)
+		for i := range nodes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				nodes[i], _ = lt.GetOrLoad(7, func(uint) (interface{}, bool) {
+					return atomic.AddInt64(&calls, 1), true
+				})
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		if c := lt.Count(); c != 1 {
+			t.Logf("Expected exactly one key to be inserted, saw %d", c)
+			t.Fail()
+		}
+		winner, _ := lt.Get(7)
+		for i, n := range nodes {
+			if n == nil || n.ID() != winner.ID() || n.Value() != winner.Value() {
+				t.Logf("Expected caller %d to be returned the inserted node %v, saw %v", i, winner, n)
+				t.Fail()
+			}
+		}
+		if c := atomic.LoadInt64(&calls); c < 1 || c > int64(len(nodes)) {
+			t.Logf("Expected between 1 and %d loader calls, saw %d", len(nodes), c)
+			t.Fail()
+		}
+	})
+}
+
+func TestLockingTree_RandomNode(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+
+	const draws = 60000
+	r := rand.New(rand.NewSource(200))
+	seen := make(map[uint]int, len(keys))
+	for i := 0; i < draws; i++ {
+		n, ok := lt.RandomNode(r)
+		if !ok {
+			t.Log("Expected a node from a non-empty tree")
+			t.FailNow()
+		}
+		seen[n.Key()]++
+	}
+	// each key is expected 10000 times, with a standard deviation of roughly 91
+	for _, k := range keys {
+		if c := seen[k]; c < 9500 || c > 10500 {
+			t.Logf("Expected key %d to be drawn roughly %d times, saw %d", k, draws/len(keys), c)
+			t.Fail()
+		}
+	}
+	if len(seen) != len(keys) {
+		t.Logf("Expected only present keys to be drawn, saw %v", seen)
+		t.Fail()
+	}
+
+	a, _ := lt.RandomNode(rand.New(rand.NewSource(1)))
+	b, _ := lt.RandomNode(rand.New(rand.NewSource(1)))
+	if a.Key() != b.Key() {
+		t.Logf("Expected identically seeded draws to match, saw %d and %d", a.Key(), b.Key())
+		t.Fail()
+	}
+	if _, ok := lt.RandomNode(nil); !ok {
+		t.Log("Expected a nil source to fall back to the default source")
+		t.Fail()
+	}
+	if _, ok := gerbst.NewLockingTree().RandomNode(r); ok {
+		t.Log("Expected no node from an empty tree")
+		t.Fail()
+	}
+}
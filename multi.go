@@ -0,0 +1,119 @@
+package gerbst
+
+import (
+	"sync"
+)
+
+// MultiTree is a binary search tree with bag semantics: rather than overwriting, each Put of an existing key appends
+// another value to that key.
+type MultiTree struct {
+	mu sync.RWMutex
+
+	root *treeNode
+
+	values uint
+}
+
+// NewMultiTree constructs a new, empty multi-value tree
+func NewMultiTree() *MultiTree {
+	mt := new(MultiTree)
+	return mt
+}
+
+// Count returns the number of distinct keys within this tree
+func (mt *MultiTree) Count() uint {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+	if mt.root == nil {
+		return 0
+	}
+	return mt.root.count
+}
+
+// ValueCount returns the total number of values stored across all keys within this tree
+func (mt *MultiTree) ValueCount() uint {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+	return mt.values
+}
+
+// Put appends value to the list of values stored under key, creating the key if it does not yet exist
+func (mt *MultiTree) Put(key uint, value interface{}) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.values++
+	if mt.root == nil {
+		mt.root = newTreeNode(key, []interface{}{value}, 1, NodeSideRoot, nil, nil, nil)
+		return
+	}
+	if tn := mt.root.find(key); tn != nil {
+		tn.setValue(append(tn.value.([]interface{}), value))
+		return
+	}
+	mt.root.Put(key, []interface{}{value})
+}
+
+// GetAll returns a copy of every value stored under key in insertion order, or false if the key is absent
+func (mt *MultiTree) GetAll(key uint) ([]interface{}, bool) {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+	if mt.root == nil || key < mt.root.loKey || key > mt.root.hiKey {
+		return nil, false
+	}
+	tn := mt.root.find(key)
+	if tn == nil {
+		return nil, false
+	}
+	values := tn.value.([]interface{})
+	out := make([]interface{}, len(values))
+	copy(out, values)
+	return out, true
+}
+
+// Delete removes key along with all of its values, returning false if the key was absent
+func (mt *MultiTree) Delete(key uint) bool {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.root == nil {
+		return false
+	}
+	tn := mt.root.find(key)
+	if tn == nil {
+		return false
+	}
+	mt.values -= uint(len(tn.value.([]interface{})))
+	deleteNode(&mt.root, tn)
+	return true
+}
+
+// DeleteValue removes the first value stored under key that is equal to value, removing the key itself once its last
+// value is gone.  Returns false if no such value was found.  Values are compared with ==, so must be comparable.
+func (mt *MultiTree) DeleteValue(key uint, value interface{}) bool {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.root == nil {
+		return false
+	}
+	tn := mt.root.find(key)
+	if tn == nil {
+		return false
+	}
+	values := tn.value.([]interface{})
+	for i, v := range values {
+		if v != value {
+			continue
+		}
+		mt.values--
+		if len(values) == 1 {
+			deleteNode(&mt.root, tn)
+			return true
+		}
+		// build a new slice so copies previously handed out remain untouched
+		remaining := make([]interface{}, 0, len(values)-1)
+		remaining = append(remaining, values[:i]...)
+		remaining = append(remaining, values[i+1:]...)
+		tn.setValue(remaining)
+		return true
+	}
+	return false
+}
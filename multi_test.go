@@ -0,0 +1,97 @@
+package gerbst_test
+
+import (
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestMultiTree(t *testing.T) {
+	mt := gerbst.NewMultiTree()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		mt.Put(k, k)
+	}
+	mt.Put(11, "a")
+	mt.Put(11, "b")
+
+	if c := mt.Count(); c != 6 {
+		t.Logf("Expected 6 distinct keys, saw %d", c)
+		t.Fail()
+	}
+	if c := mt.ValueCount(); c != 8 {
+		t.Logf("Expected 8 values, saw %d", c)
+		t.Fail()
+	}
+
+	t.Run("get_all", func(t *testing.T) {
+		vals, ok := mt.GetAll(11)
+		if !ok {
+			t.Log("Expected key 11 to exist")
+			t.FailNow()
+		}
+		expected := []interface{}{uint(11), "a", "b"}
+		if len(vals) != len(expected) {
+			t.Logf("Expected values %v, saw %v", expected, vals)
+			t.FailNow()
+		}
+		for i := range expected {
+			if vals[i] != expected[i] {
+				t.Logf("Expected values %v in insertion order, saw %v", expected, vals)
+				t.Fail()
+				break
+			}
+		}
+		if _, ok := mt.GetAll(8); ok {
+			t.Log("Expected absent key 8 to return false")
+			t.Fail()
+		}
+	})
+
+	t.Run("delete_value", func(t *testing.T) {
+		if !mt.DeleteValue(11, "a") {
+			t.Log("Expected to delete value \"a\" from key 11")
+			t.Fail()
+		}
+		if vals, _ := mt.GetAll(11); len(vals) != 2 || vals[0] != uint(11) || vals[1] != "b" {
+			t.Logf("Expected remaining values [11 b], saw %v", vals)
+			t.Fail()
+		}
+		if mt.DeleteValue(11, "a") {
+			t.Log("Expected deleting an already-removed value to return false")
+			t.Fail()
+		}
+		if !mt.DeleteValue(9, uint(9)) {
+			t.Log("Expected to delete the only value of key 9")
+			t.Fail()
+		}
+		if _, ok := mt.GetAll(9); ok {
+			t.Log("Expected key 9 to be removed along with its last value")
+			t.Fail()
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if !mt.Delete(11) {
+			t.Log("Expected to delete key 11")
+			t.Fail()
+		}
+		if _, ok := mt.GetAll(11); ok {
+			t.Log("Expected key 11 to be gone")
+			t.Fail()
+		}
+		if c := mt.Count(); c != 4 {
+			t.Logf("Expected 4 distinct keys, saw %d", c)
+			t.Fail()
+		}
+		if c := mt.ValueCount(); c != 4 {
+			t.Logf("Expected 4 values, saw %d", c)
+			t.Fail()
+		}
+		for _, k := range []uint{7, 12, 82, 90} {
+			if _, ok := mt.GetAll(k); !ok {
+				t.Logf("Expected key %d to survive", k)
+				t.Fail()
+			}
+		}
+	})
+}
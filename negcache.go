@@ -0,0 +1,63 @@
+package gerbst
+
+import (
+	"sync"
+)
+
+// negativeCache remembers a bounded number of keys recently found to be absent from a tree, forgetting the oldest once
+// full.  It has its own lock so that misses may be recorded while only the tree's read lock is held.
+type negativeCache struct {
+	mu    sync.Mutex
+	keys  map[uint]struct{}
+	order []uint
+	next  int
+}
+
+func newNegativeCache(size int) *negativeCache {
+	nc := new(negativeCache)
+	nc.keys = make(map[uint]struct{}, size)
+	nc.order = make([]uint, 0, size)
+	return nc
+}
+
+// has returns true if key was recorded as absent
+func (nc *negativeCache) has(key uint) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	_, ok := nc.keys[key]
+	return ok
+}
+
+// add records key as absent, replacing the oldest recorded key if the cache is full
+func (nc *negativeCache) add(key uint) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if _, ok := nc.keys[key]; ok {
+		return
+	}
+	if len(nc.order) < cap(nc.order) {
+		nc.order = append(nc.order, key)
+	} else {
+		delete(nc.keys, nc.order[nc.next])
+		nc.order[nc.next] = key
+		nc.next = (nc.next + 1) % len(nc.order)
+	}
+	nc.keys[key] = struct{}{}
+}
+
+// remove forgets key.  Its slot in the eviction order is left in place, so should key be recorded again before that
+// slot is reused it may be forgotten early, which costs no more than a lookup.
+func (nc *negativeCache) remove(key uint) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	delete(nc.keys, key)
+}
+
+// clear forgets every key
+func (nc *negativeCache) clear() {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	nc.keys = make(map[uint]struct{}, cap(nc.order))
+	nc.order = nc.order[:0]
+	nc.next = 0
+}
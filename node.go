@@ -2,21 +2,35 @@ package gerbst
 
 import (
 	"fmt"
+	"math/bits"
+	"sync/atomic"
 
 	"github.com/disiqueira/gotree"
 )
 
-// Node represents the exportable representation of a given node within a tree
+// Node represents the exportable representation of a given node within a tree.  It is a snapshot of a single key and
+// value, holding no links to other nodes, so a tree's contents are moved elsewhere with Entries, Flatten, or Drain
+// rather than through its nodes.
 type Node struct {
+	id    uint64
 	key   uint
 	value interface{}
 	depth uint
 	side  NodeSide
 }
 
-// newNode constructs the actual node instance
+// lastNodeID is the most recent id handed out by nextNodeID
+var lastNodeID uint64
+
+// nextNodeID returns an id unique to this process
+func nextNodeID() uint64 {
+	return atomic.AddUint64(&lastNodeID, 1)
+}
+
+// newNode constructs the actual node instance, with a new id
 func newNode(key uint, value interface{}, depth uint, side NodeSide) *Node {
 	n := new(Node)
+	n.id = nextNodeID()
 	n.key = key
 	n.value = value
 	n.depth = depth
@@ -24,6 +38,23 @@ func newNode(key uint, value interface{}, depth uint, side NodeSide) *Node {
 	return n
 }
 
+// reseat returns a copy of this node with the provided value, depth, and side, keeping its id
+func (n *Node) reseat(value interface{}, depth uint, side NodeSide) *Node {
+	c := new(Node)
+	*c = *n
+	c.value = value
+	c.depth = depth
+	c.side = side
+	return c
+}
+
+// ID returns an identifier assigned when this node's key was inserted, unique within this process.  Every *Node later
+// returned for the same key carries the same ID for as long as the key remains in the tree, even as its value changes
+// or the tree is restructured by rebalancing or rotation.  Deleting and re-inserting a key assigns a new ID.
+func (n *Node) ID() uint64 {
+	return n.id
+}
+
 // Key returns this node's key
 func (n *Node) Key() uint {
 	return n.key
@@ -44,6 +75,22 @@ func (n *Node) Side() NodeSide {
 	return n.side
 }
 
+// CompareNodes returns -1 if a's key is less than b's, 1 if it is greater, and 0 if they are equal.  It may be used to
+// sort a slice of nodes by key:
+//
+//	sort.Slice(nodes, func(i, j int) bool { return gerbst.CompareNodes(nodes[i], nodes[j]) < 0 })
+func CompareNodes(a, b *Node) int {
+	switch {
+	case a.key < b.key:
+		return -1
+	case a.key > b.key:
+		return 1
+
+	default:
+		return 0
+	}
+}
+
 type treeNode struct {
 	*Node
 
@@ -65,7 +112,13 @@ type treeNode struct {
 
 func newTreeNode(key uint, value interface{}, depth uint, side NodeSide, parent, left, right *treeNode) *treeNode {
 	tn := new(treeNode)
-	tn.Node = newNode(key, value, depth, side)
+	tn.init(newNode(key, value, depth, side), parent, left, right)
+	return tn
+}
+
+// init prepares a zero-value treeNode to hold node
+func (tn *treeNode) init(node *Node, parent, left, right *treeNode) {
+	tn.Node = node
 
 	// set nodes
 	tn.parent = parent
@@ -77,8 +130,12 @@ func newTreeNode(key uint, value interface{}, depth uint, side NodeSide, parent,
 	tn.depthMax = tn.depth
 	tn.loKey = tn.key
 	tn.hiKey = tn.key
+}
 
-	return tn
+// setValue replaces this node's exported representation with one carrying the new value, leaving any previously
+// returned *Node untouched
+func (tn *treeNode) setValue(value interface{}) {
+	tn.Node = tn.Node.reseat(value, tn.depth, tn.side)
 }
 
 // Left returns the left branch of this tree, if there is one
@@ -131,20 +188,21 @@ func (tn *treeNode) GetRecurse(key uint) (*Node, bool) {
 	return nil, false
 }
 
-func (tn *treeNode) Put(key uint, value interface{}) {
+// Put inserts a new node or updates the value of an existing node, returning true if a new node was created
+func (tn *treeNode) Put(key uint, value interface{}) bool {
 	n := tn
 	for n != nil {
 		// if we need to update the existing node
 		if n.key == key {
-			n.Node = newNode(key, value, tn.depth, tn.side)
-			return
+			n.setValue(value)
+			return false
 		} else if n.key > key {
 			if n.left == nil {
 				// if we get here, key is lower than local and we have no left node, so create one
 				// and move on.
 				n.left = newTreeNode(key, value, n.depth+1, NodeSideLeft, n, nil, nil)
 				updateMeta(n.left)
-				return
+				return true
 			} else {
 				// set parent to local and update local to left side of local
 				n = n.left
@@ -154,35 +212,40 @@ func (tn *treeNode) Put(key uint, value interface{}) {
 			// and move on.
 			n.right = newTreeNode(key, value, n.depth+1, NodeSideRight, n, nil, nil)
 			updateMeta(n.right)
-			return
+			return true
 		} else {
 			// update parent to n and update local to right side of local
 			n = n.right
 		}
 	}
+	return false
 }
 
-func (tn *treeNode) PutRecurse(key uint, value interface{}) {
+// PutRecurse inserts a new node or updates the value of an existing node using recursion, returning true if a new
+// node was created
+func (tn *treeNode) PutRecurse(key uint, value interface{}) bool {
 	if tn.key == key {
-		tn.Node = newNode(key, value, tn.depth, tn.side)
+		tn.setValue(value)
+		return false
 	} else if tn.key > key {
 		if tn.left == nil {
 			tn.left = newTreeNode(key, value, tn.depth+1, NodeSideLeft, tn, nil, nil)
 			updateMeta(tn.left)
-		} else {
-			tn.left.PutRecurse(key, value)
+			return true
 		}
+		return tn.left.PutRecurse(key, value)
 	} else if tn.right == nil {
 		tn.right = newTreeNode(key, value, tn.depth+1, NodeSideRight, tn, nil, nil)
 		updateMeta(tn.right)
-	} else {
-		tn.right.PutRecurse(key, value)
+		return true
 	}
+	return tn.right.PutRecurse(key, value)
 }
 
 func (tn *treeNode) metaString() string {
 	return fmt.Sprintf(
-		"node=%p; parent=%p; side=%q, count=%d; countLeft=%d; countRight=%d; depth=%d; depthMax=%d; depthMaxLeft=%d; depthMaxRight=%d",
+		"key=%d; node=%p; parent=%p; side=%q, count=%d; countLeft=%d; countRight=%d; depth=%d; depthMax=%d; depthMaxLeft=%d; depthMaxRight=%d; loKey=%d; hiKey=%d",
+		tn.key,
 		tn,
 		tn.parent,
 		tn.side,
@@ -192,7 +255,9 @@ func (tn *treeNode) metaString() string {
 		tn.depth,
 		tn.depthMax,
 		tn.depthMaxLeft,
-		tn.depthMaxRight)
+		tn.depthMaxRight,
+		tn.loKey,
+		tn.hiKey)
 }
 
 // String returns a printable sum of this node in the format of SIDE[KEY(VALUE)]
@@ -200,20 +265,29 @@ func (tn *treeNode) String() string {
 	return fmt.Sprintf("%s[%d(%v)]", tn.side, tn.key, tn.value)
 }
 
-// buildTreePrinter recursively builds our tree printer for us.  This was included so I can be lazy and not
-// write my own visual inspector
-func (tn *treeNode) buildTreePrinter() gotree.Tree {
+// compactString returns a printable sum of this node in the format of SIDE[KEY], omitting the value when it is nil or
+// simply a copy of the key, and SIDE[KEY(VALUE)] otherwise
+func (tn *treeNode) compactString() string {
+	if tn.value == nil || tn.value == interface{}(tn.key) {
+		return fmt.Sprintf("%s[%d]", tn.side, tn.key)
+	}
+	return tn.String()
+}
+
+// buildTreePrinter recursively builds our tree printer for us, using label to produce the text of each node.  This was
+// included so I can be lazy and not write my own visual inspector
+func (tn *treeNode) buildTreePrinter(label func(*treeNode) string) gotree.Tree {
 	// construct new tree
-	root := gotree.New(tn.String())
+	root := gotree.New(label(tn))
 
 	// add left branch
 	if tn.left != nil {
-		root.AddTree(tn.left.buildTreePrinter())
+		root.AddTree(tn.left.buildTreePrinter(label))
 	}
 
 	// add right branch
 	if tn.right != nil {
-		root.AddTree(tn.right.buildTreePrinter())
+		root.AddTree(tn.right.buildTreePrinter(label))
 	}
 
 	// we did it.
@@ -262,3 +336,616 @@ func updateMeta(src *treeNode) {
 		parent = parent.parent
 	}
 }
+
+// walkPreOrder visits this node followed by its left and right branches, halting when fn returns false
+func (tn *treeNode) walkPreOrder(fn func(*treeNode) bool) bool {
+	if !fn(tn) {
+		return false
+	}
+	if tn.left != nil && !tn.left.walkPreOrder(fn) {
+		return false
+	}
+	if tn.right != nil && !tn.right.walkPreOrder(fn) {
+		return false
+	}
+	return true
+}
+
+// walkPreOrderRightFirst visits this node followed by its right and left branches, mirroring walkPreOrder, halting when
+// fn returns false
+func (tn *treeNode) walkPreOrderRightFirst(fn func(*treeNode) bool) bool {
+	if !fn(tn) {
+		return false
+	}
+	if tn.right != nil && !tn.right.walkPreOrderRightFirst(fn) {
+		return false
+	}
+	if tn.left != nil && !tn.left.walkPreOrderRightFirst(fn) {
+		return false
+	}
+	return true
+}
+
+// walkInOrder visits the left branch, this node, then the right branch, halting when fn returns false
+func (tn *treeNode) walkInOrder(fn func(*treeNode) bool) bool {
+	if tn.left != nil && !tn.left.walkInOrder(fn) {
+		return false
+	}
+	if !fn(tn) {
+		return false
+	}
+	if tn.right != nil && !tn.right.walkInOrder(fn) {
+		return false
+	}
+	return true
+}
+
+// walkRange calls fn with each node of this subtree whose key lies within [lo, hi] in ascending key order, skipping
+// branches that lie wholly outside the range.  Halts and returns false as soon as fn returns false.
+func (tn *treeNode) walkRange(lo, hi uint, fn func(*treeNode) bool) bool {
+	if tn.hiKey < lo || tn.loKey > hi {
+		return true
+	}
+	if tn.left != nil && tn.key > lo && !tn.left.walkRange(lo, hi, fn) {
+		return false
+	}
+	if tn.key >= lo && tn.key <= hi && !fn(tn) {
+		return false
+	}
+	if tn.right != nil && tn.key < hi && !tn.right.walkRange(lo, hi, fn) {
+		return false
+	}
+	return true
+}
+
+// path returns each node from this one down to the node with the provided key, or false if the key is absent
+func (tn *treeNode) path(key uint) ([]*treeNode, bool) {
+	path := make([]*treeNode, 0, tn.depthMax-tn.depth+1)
+	n := tn
+	for n != nil {
+		path = append(path, n)
+		if n.key == key {
+			return path, true
+		} else if n.key > key {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return nil, false
+}
+
+// pathLength returns the sum of the depths of every node in this subtree
+func (tn *treeNode) pathLength() uint {
+	var sum uint
+	tn.walkPreOrder(func(n *treeNode) bool {
+		sum += n.depth
+		return true
+	})
+	return sum
+}
+
+// diameter returns the height of this subtree in nodes along with the number of edges on the longest path between
+// any two of its nodes, combining the heights of each node's branches in post-order
+func (tn *treeNode) diameter() (height, diameter uint) {
+	var lh, ld, rh, rd uint
+	if tn.left != nil {
+		lh, ld = tn.left.diameter()
+	}
+	if tn.right != nil {
+		rh, rd = tn.right.diameter()
+	}
+
+	// the longest path through this node joins the deepest leaf on each side
+	diameter = lh + rh
+	if ld > diameter {
+		diameter = ld
+	}
+	if rd > diameter {
+		diameter = rd
+	}
+
+	if lh > rh {
+		return lh + 1, diameter
+	}
+	return rh + 1, diameter
+}
+
+// heightBalanced returns true if, at every node within this subtree, the heights of the left and right branches differ
+// by at most one
+func (tn *treeNode) heightBalanced() bool {
+	balanced := true
+	tn.walkPreOrder(func(n *treeNode) bool {
+		var lh, rh uint
+		if n.left != nil {
+			lh = n.depthMaxLeft - n.depth
+		}
+		if n.right != nil {
+			rh = n.depthMaxRight - n.depth
+		}
+		balanced = lh <= rh+1 && rh <= lh+1
+		return balanced
+	})
+	return balanced
+}
+
+// spineLength returns the number of nodes on the path from this node following only right branches, including itself
+func (tn *treeNode) spineLength() uint {
+	var l uint
+	for n := tn; n != nil; n = n.right {
+		l++
+	}
+	return l
+}
+
+// dswRotations returns the number of single rotations the Day-Stout-Warren algorithm performs to balance a tree of
+// count nodes whose root begins a right spine of spine nodes.  Flattening into a vine takes one right rotation for each
+// node not already on the spine, and compression then takes a number of left rotations determined by count alone.
+func dswRotations(count, spine uint) uint {
+	rotations := count - spine
+	// the largest complete tree that fits, with the remainder forming a partial bottom level
+	m := uint(1)<<(bits.Len(count+1)-1) - 1
+	rotations += count - m
+	for m > 1 {
+		m /= 2
+		rotations += m
+	}
+	return rotations
+}
+
+// balanceDSW balances the subtree rooted at root in place using the Day-Stout-Warren algorithm, relinking its existing
+// nodes, and returns the new root along with the number of rotations performed.  Only branches are relinked, so the
+// caller must rebuild the metadata of the returned subtree.
+func balanceDSW(root *treeNode) (*treeNode, uint) {
+	var rotations uint
+	// a pseudo-root lets rotations at the real root be performed like any other
+	pseudo := &treeNode{right: root}
+
+	// flatten into a vine by rotating right at each node of the spine until it has no left branch
+	for tail, rest := pseudo, root; rest != nil; {
+		if rest.left == nil {
+			tail, rest = rest, rest.right
+			continue
+		}
+		l := rest.left
+		rest.left = l.right
+		l.right = rest
+		tail.right = l
+		rest = l
+		rotations++
+	}
+
+	// compress rotates left at every other node of the vine, count times, halving its length
+	compress := func(count uint) {
+		scanner := pseudo
+		for i := uint(0); i < count; i++ {
+			child := scanner.right
+			scanner.right = child.right
+			scanner = scanner.right
+			child.right = scanner.left
+			scanner.left = child
+			rotations++
+		}
+	}
+
+	// first place the nodes beyond the largest complete tree that fits as the partial bottom level
+	count := root.count
+	m := uint(1)<<(bits.Len(count+1)-1) - 1
+	compress(count - m)
+	for m > 1 {
+		m /= 2
+		compress(m)
+	}
+
+	return pseudo.right, rotations
+}
+
+// refreshMeta recomputes this node's aggregate metadata from its immediate branches, whose own metadata must already
+// be correct
+func (tn *treeNode) refreshMeta() {
+	tn.count = 1
+	tn.countLeft = 0
+	tn.countRight = 0
+	tn.depthMax = tn.depth
+	tn.depthMaxLeft = 0
+	tn.depthMaxRight = 0
+	tn.loKey = tn.key
+	tn.hiKey = tn.key
+
+	if tn.left != nil {
+		tn.countLeft = tn.left.count
+		tn.depthMaxLeft = tn.left.depthMax
+		tn.loKey = tn.left.loKey
+		if tn.depthMax < tn.depthMaxLeft {
+			tn.depthMax = tn.depthMaxLeft
+		}
+	}
+	if tn.right != nil {
+		tn.countRight = tn.right.count
+		tn.depthMaxRight = tn.right.depthMax
+		tn.hiKey = tn.right.hiKey
+		if tn.depthMax < tn.depthMaxRight {
+			tn.depthMax = tn.depthMaxRight
+		}
+	}
+
+	tn.count += tn.countLeft + tn.countRight
+}
+
+// rebuildMeta seats this node beneath parent at the provided depth and side, recursively doing the same for each of
+// its branches before recomputing its aggregate metadata
+func (tn *treeNode) rebuildMeta(parent *treeNode, depth uint, side NodeSide) {
+	tn.parent = parent
+	if tn.depth != depth || tn.side != side {
+		tn.Node = tn.Node.reseat(tn.value, depth, side)
+	}
+	if tn.left != nil {
+		tn.left.rebuildMeta(tn, depth+1, NodeSideLeft)
+	}
+	if tn.right != nil {
+		tn.right.rebuildMeta(tn, depth+1, NodeSideRight)
+	}
+	tn.refreshMeta()
+}
+
+// validateOrder returns an error if any node within this subtree violates binary search tree ordering.  It relies on
+// loKey and hiKey, so metadata must be up to date.
+func (tn *treeNode) validateOrder() error {
+	var err error
+	tn.walkPreOrder(func(n *treeNode) bool {
+		if n.left != nil && n.left.hiKey >= n.key {
+			err = fmt.Errorf("left branch of key %d contains key %d", n.key, n.left.hiKey)
+		} else if n.right != nil && n.right.loKey <= n.key {
+			err = fmt.Errorf("right branch of key %d contains key %d", n.key, n.right.loKey)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// clone returns a deep copy of this subtree beneath parent with each key passed through rekey.  Metadata is copied
+// as-is, so if rekey alters keys the caller must rebuild it.
+func (tn *treeNode) clone(parent *treeNode, rekey func(uint) uint) *treeNode {
+	c := new(treeNode)
+	*c = *tn
+	c.Node = newNode(rekey(tn.key), tn.value, tn.depth, tn.side)
+	c.parent = parent
+	if tn.left != nil {
+		c.left = tn.left.clone(c, rekey)
+	}
+	if tn.right != nil {
+		c.right = tn.right.clone(c, rekey)
+	}
+	return c
+}
+
+// find returns the node with the provided key within this subtree, or nil if there isn't one
+func (tn *treeNode) find(key uint) *treeNode {
+	n := tn
+	for n != nil && n.key != key {
+		if n.key > key {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return n
+}
+
+// deleteNode removes tn from the tree whose root is pointed to by root.  If tn has both branches its in-order
+// successor is moved into its place.  Every node that changes position is re-seated, and the metadata of each of tn's
+// former ancestors is refreshed.
+func deleteNode(root **treeNode, tn *treeNode) {
+	var replacement *treeNode
+	if tn.left == nil {
+		replacement = tn.right
+	} else if tn.right == nil {
+		replacement = tn.left
+	} else {
+		// the successor has no left branch, so it may be spliced out of its current position by promoting its right
+		succ := tn.right
+		for succ.left != nil {
+			succ = succ.left
+		}
+		if succ != tn.right {
+			succ.parent.left = succ.right
+			if succ.right != nil {
+				succ.right.parent = succ.parent
+			}
+			succ.right = tn.right
+		}
+		succ.left = tn.left
+		replacement = succ
+	}
+
+	parent := tn.parent
+	if parent == nil {
+		*root = replacement
+	} else if parent.left == tn {
+		parent.left = replacement
+	} else {
+		parent.right = replacement
+	}
+
+	if replacement != nil {
+		replacement.rebuildMeta(parent, tn.depth, tn.side)
+	}
+	for p := parent; p != nil; p = p.parent {
+		p.refreshMeta()
+	}
+
+	tn.parent = nil
+	tn.left = nil
+	tn.right = nil
+}
+
+// rotate performs a tree rotation around tn within the tree whose root is pointed to by root, promoting tn's right
+// child when left is true and its left child otherwise.  The required child must be present.  Every node of the
+// promoted subtree is re-seated, and the metadata of each ancestor is refreshed.
+func rotate(root **treeNode, tn *treeNode, left bool) {
+	var pivot *treeNode
+	if left {
+		pivot = tn.right
+		tn.right = pivot.left
+		pivot.left = tn
+	} else {
+		pivot = tn.left
+		tn.left = pivot.right
+		pivot.right = tn
+	}
+
+	parent := tn.parent
+	if parent == nil {
+		*root = pivot
+	} else if parent.left == tn {
+		parent.left = pivot
+	} else {
+		parent.right = pivot
+	}
+
+	pivot.rebuildMeta(parent, tn.depth, tn.side)
+	for p := parent; p != nil; p = p.parent {
+		p.refreshMeta()
+	}
+}
+
+// lowest returns the node with the lowest key within this subtree
+func (tn *treeNode) lowest() *treeNode {
+	n := tn
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+// highest returns the node with the highest key within this subtree
+func (tn *treeNode) highest() *treeNode {
+	n := tn
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// deepest returns the node at the maximum depth of this subtree, preferring the lowest key when there are several
+func (tn *treeNode) deepest() *treeNode {
+	n := tn
+	for {
+		if n.left != nil && n.left.depthMax == tn.depthMax {
+			n = n.left
+		} else if n.right != nil && n.right.depthMax == tn.depthMax {
+			n = n.right
+		} else {
+			return n
+		}
+	}
+}
+
+// checkMeta verifies that this subtree is seated beneath parent at the provided depth and side, and that every node's
+// aggregate metadata agrees with its branches.  Returns the first discrepancy found.
+func (tn *treeNode) checkMeta(parent *treeNode, depth uint, side NodeSide) error {
+	if tn.parent != parent {
+		return fmt.Errorf("key %d: parent pointer %p does not match actual parent %p", tn.key, tn.parent, parent)
+	}
+	if tn.depth != depth {
+		return fmt.Errorf("key %d: depth is %d, expected %d", tn.key, tn.depth, depth)
+	}
+	if tn.side != side {
+		return fmt.Errorf("key %d: side is %s, expected %s", tn.key, tn.side, side)
+	}
+	if tn.left != nil {
+		if err := tn.left.checkMeta(tn, depth+1, NodeSideLeft); err != nil {
+			return err
+		}
+	}
+	if tn.right != nil {
+		if err := tn.right.checkMeta(tn, depth+1, NodeSideRight); err != nil {
+			return err
+		}
+	}
+
+	// compare against a freshly computed copy of this node's metadata
+	expected := *tn
+	expected.refreshMeta()
+	if tn.count != expected.count || tn.countLeft != expected.countLeft || tn.countRight != expected.countRight {
+		return fmt.Errorf("key %d: counts are %d/%d/%d, expected %d/%d/%d",
+			tn.key, tn.count, tn.countLeft, tn.countRight, expected.count, expected.countLeft, expected.countRight)
+	}
+	if tn.depthMax != expected.depthMax || tn.depthMaxLeft != expected.depthMaxLeft || tn.depthMaxRight != expected.depthMaxRight {
+		return fmt.Errorf("key %d: max depths are %d/%d/%d, expected %d/%d/%d",
+			tn.key, tn.depthMax, tn.depthMaxLeft, tn.depthMaxRight, expected.depthMax, expected.depthMaxLeft, expected.depthMaxRight)
+	}
+	if tn.loKey != expected.loKey || tn.hiKey != expected.hiKey {
+		return fmt.Errorf("key %d: key bounds are [%d, %d], expected [%d, %d]",
+			tn.key, tn.loKey, tn.hiKey, expected.loKey, expected.hiKey)
+	}
+	return nil
+}
+
+// entries returns the key and value of every node within this subtree in ascending key order
+func (tn *treeNode) entries() []Entry {
+	entries := make([]Entry, 0, tn.count)
+	tn.walkInOrder(func(n *treeNode) bool {
+		entries = append(entries, Entry{Key: n.key, Value: n.value})
+		return true
+	})
+	return entries
+}
+
+// buildBalanced constructs a height-balanced subtree from sorted, unique keys, calling value to obtain each node's
+// value.  If gaps is not nil it must hold len(keys)+1 subtrees, which are attached in order to the empty branch slots
+// between the constructed nodes.  Metadata is NOT computed, so callers must rebuild it once the subtree is seated.
+func buildBalanced(keys []uint, value func(i int) interface{}, gaps []*treeNode) *treeNode {
+	var build func(lo, hi int) *treeNode
+	build = func(lo, hi int) *treeNode {
+		if lo >= hi {
+			if gaps != nil {
+				return gaps[lo]
+			}
+			return nil
+		}
+		mid := lo + (hi-lo)/2
+		tn := newTreeNode(keys[mid], value(mid), 0, NodeSideRoot, nil, nil, nil)
+		tn.left = build(lo, mid)
+		tn.right = build(mid+1, hi)
+		return tn
+	}
+	return build(0, len(keys))
+}
+
+// attachPoint returns the node with the provided key and true if it exists within this subtree, otherwise the node
+// beneath which a new node with that key would be attached and false
+func (tn *treeNode) attachPoint(key uint) (*treeNode, bool) {
+	n := tn
+	for {
+		var next *treeNode
+		if n.key == key {
+			return n, true
+		} else if n.key > key {
+			next = n.left
+		} else {
+			next = n.right
+		}
+		if next == nil {
+			return n, false
+		}
+		n = next
+	}
+}
+
+// floor returns the node with the greatest key less than or equal to the provided key within this subtree, or nil
+func (tn *treeNode) floor(key uint) *treeNode {
+	var best *treeNode
+	for n := tn; n != nil; {
+		if n.key == key {
+			return n
+		} else if n.key > key {
+			n = n.left
+		} else {
+			best = n
+			n = n.right
+		}
+	}
+	return best
+}
+
+// ceiling returns the node with the lowest key greater than or equal to the provided key within this subtree, or nil
+func (tn *treeNode) ceiling(key uint) *treeNode {
+	var best *treeNode
+	for n := tn; n != nil; {
+		if n.key == key {
+			return n
+		} else if n.key < key {
+			n = n.right
+		} else {
+			best = n
+			n = n.left
+		}
+	}
+	return best
+}
+
+// onlyChild returns this node's sole branch, or nil if it has zero or two branches
+func (tn *treeNode) onlyChild() *treeNode {
+	if tn.left == nil {
+		return tn.right
+	} else if tn.right == nil {
+		return tn.left
+	}
+	return nil
+}
+
+// walkInOut calls enter for this node in pre-order, descends into its branches only if enter returned true, then
+// calls leave for this node in post-order
+func (tn *treeNode) walkInOut(enter func(*treeNode) bool, leave func(*treeNode)) {
+	if enter(tn) {
+		if tn.left != nil {
+			tn.left.walkInOut(enter, leave)
+		}
+		if tn.right != nil {
+			tn.right.walkInOut(enter, leave)
+		}
+	}
+	leave(tn)
+}
+
+// rank returns the number of keys within this subtree lower than the provided key, and the node holding the key or
+// nil if it is absent
+func (tn *treeNode) rank(key uint) (uint, *treeNode) {
+	var r uint
+	for n := tn; n != nil; {
+		if n.key == key {
+			return r + n.countLeft, n
+		} else if n.key > key {
+			n = n.left
+		} else {
+			r += n.countLeft + 1
+			n = n.right
+		}
+	}
+	return r, nil
+}
+
+// successor returns the node with the next highest key in the tree, or nil if this node holds the highest key
+func (tn *treeNode) successor() *treeNode {
+	if tn.right != nil {
+		return tn.right.lowest()
+	}
+	n := tn
+	for n.parent != nil && n.parent.right == n {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// predecessor returns the node with the next lowest key in the tree, or nil if this node holds the lowest key
+func (tn *treeNode) predecessor() *treeNode {
+	if tn.left != nil {
+		return tn.left.highest()
+	}
+	n := tn
+	for n.parent != nil && n.parent.left == n {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// selectRank returns the node at the provided zero-based position in ascending key order within this subtree, or nil
+// if the position is out of range
+func (tn *treeNode) selectRank(i uint) *treeNode {
+	if i >= tn.count {
+		return nil
+	}
+	n := tn
+	for n != nil {
+		if i < n.countLeft {
+			n = n.left
+		} else if i == n.countLeft {
+			return n
+		} else {
+			i -= n.countLeft + 1
+			n = n.right
+		}
+	}
+	return nil
+}
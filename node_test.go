@@ -0,0 +1,38 @@
+package gerbst_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestCompareNodes(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+
+	nodes := make([]*gerbst.Node, 0, len(keys))
+	for _, k := range keys {
+		n, _ := lt.Get(k)
+		nodes = append(nodes, n)
+	}
+	rand.New(rand.NewSource(158)).Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+
+	sort.Slice(nodes, func(i, j int) bool { return gerbst.CompareNodes(nodes[i], nodes[j]) < 0 })
+	for i, expected := range []uint{7, 9, 11, 12, 82, 90} {
+		if k := nodes[i].Key(); k != expected {
+			t.Logf("Expected key %d at index %d, saw %d", expected, i, k)
+			t.Fail()
+		}
+	}
+
+	if c := gerbst.CompareNodes(nodes[0], nodes[0]); c != 0 {
+		t.Logf("Expected a node to compare equal to itself, saw %d", c)
+		t.Fail()
+	}
+	if c := gerbst.CompareNodes(nodes[1], nodes[0]); c != 1 {
+		t.Logf("Expected greater key to compare as 1, saw %d", c)
+		t.Fail()
+	}
+}
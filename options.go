@@ -0,0 +1,121 @@
+package gerbst
+
+// LockingTreeOption configures optional behavior of a LockingTree at construction
+type LockingTreeOption func(lt *LockingTree)
+
+// WithCompactPrinting causes StringTree and friends to render a node as SIDE[KEY] rather than SIDE[KEY(VALUE)] when
+// its value is nil or equal to its key, as is the case for trees built by NewLockingTreeWithKeys
+func WithCompactPrinting() LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.compactPrinting = true
+	}
+}
+
+// CollisionResolverFunc is used in conjunction with WithCollisionResolver to choose the value kept when a key that
+// already exists is Put again
+type CollisionResolverFunc = func(old, new interface{}) interface{}
+
+// WithCollisionResolver causes Put of an existing key to store the value returned by fn, given the existing and
+// incoming values, rather than always storing the incoming value
+func WithCollisionResolver(fn CollisionResolverFunc) LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.collisionResolver = fn
+	}
+}
+
+// WithDepthBase sets the depth at which the root node is placed, and so the depth reported by Node.Depth, Depth, and
+// the DepthMax family.  Trees default to DepthBaseOne, placing the root at depth 1.  Height is unaffected.
+func WithDepthBase(base DepthBase) LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.depthBase = base
+	}
+}
+
+// WithNegativeCache remembers up to size keys that Get recently failed to find, so that repeated lookups of a missing
+// key return without searching the tree.  The oldest key is forgotten once the cache is full, and a key is forgotten as
+// soon as it is inserted.  A size less than 1 disables the cache.
+func WithNegativeCache(size int) LockingTreeOption {
+	return func(lt *LockingTree) {
+		if size < 1 {
+			lt.negCache = nil
+			return
+		}
+		lt.negCache = newNegativeCache(size)
+	}
+}
+
+// WithAutoRebalance rebuilds the tree into a height-balanced shape whenever an insertion leaves its Height greater than
+// maxHeightFactor * log2(Count).  As each rebuild is O(n), maxHeightFactor should be comfortably above 1: a balanced
+// tree is already about log2(Count) tall, so factors near 1 rebuild on almost every insertion.  A maxHeightFactor of 0
+// or less disables automatic rebalancing.
+func WithAutoRebalance(maxHeightFactor float64) LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.autoRebalance = maxHeightFactor
+	}
+}
+
+// WithValueIndex maintains an index from each value to the keys holding it, updated on every insertion, update, and
+// removal, so that KeysForValue is a single map lookup rather than a scan of every node.  Values are used as map keys,
+// so every value put into the tree must be comparable: putting an uncomparable value such as a slice panics.
+func WithValueIndex() LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.valueIndex = make(valueIndex)
+	}
+}
+
+// WithComparisonCounter tallies the number of key comparisons performed by lookups, exposed through ComparisonCount.
+// Each lookup counts one comparison per node on the path it follows.  Keys rejected by the tree's bounds, or by
+// WithNegativeCache, count none.  Counting requires walking each path a second time, so this is intended for
+// profiling rather than production use.
+func WithComparisonCounter() LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.comparisons = new(uint64)
+	}
+}
+
+// WithKeyNormalizer passes every key given to Put, PutRecurse, PutChecked, PutWithNeighbors, PutAt, Increment, Update,
+// Get, GetRecurse, GetWithRank, GetOrLoad, GetMany, GetNearest, Depth, PathCost, InsertionSide, Contains, ContainsAll,
+// ContainsAny, WouldAdd, IsAncestor, DivergencePoint, RotateLeft, RotateRight, and Delete, or to
+// NewBalancedTreeWithSortedKeys, through fn before it is used, so that, for example, keys may be masked into buckets.
+// Keys that normalize to the same value share a single node.  fn must be deterministic.  Keys merged in from another
+// tree by Graft, Union, Intersect, and Difference, and those produced by Rekey, are normalized too.  Methods taking a
+// range or bound, such as Range, Floor, Rank, and InBounds, use keys as given and do not normalize.
+func WithKeyNormalizer(fn func(uint) uint) LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.keyNormalizer = fn
+	}
+}
+
+// WithRandomizedInsertPriority keeps the tree balanced in expectation, regardless of the order keys are inserted in,
+// by maintaining it as a treap.  Each key is given a pseudo-random priority derived from seed, and after every
+// insertion and before every removal nodes are rotated so that no node holds a higher priority than its parent.  The
+// tree remains an ordinary binary search tree by key, so other methods behave as usual, except that PutAt cannot place
+// a key at a chosen position and returns ErrPriorityShaped.  Rebalance, BalanceDSW, DeleteFunc, RotateLeft, and
+// RotateRight disregard priorities and so weaken the expected balance of later operations.
+func WithRandomizedInsertPriority(seed int64) LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.treap = true
+		lt.treapSeed = uint64(seed)
+	}
+}
+
+// WithReentrancyDetection panics with a descriptive message, rather than deadlocking, when a callback run while the
+// write lock is held, such as those given to Update, MapValues, or WithCollisionResolver, calls back into the same
+// tree on the same goroutine.  Identifying the calling goroutine is slow, so this is intended for debugging only.
+func WithReentrancyDetection() LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.reentrancy = true
+	}
+}
+
+// WithInsertLimit limits the tree to holding limit nodes, refusing new keys once it is full rather than evicting
+// existing ones as the cap set by SetMaxCount does.  Updates to existing keys are always allowed.  Put, PutRecurse, and
+// Update silently drop a refused key, so use PutErr, PutChecked, Increment, or PutAt, which return ErrTreeFull, to
+// learn of it.  Constructors such as NewLockingTreeWithKeys and NewBalancedTreeWithSortedKeys likewise drop keys
+// beyond the limit, while NewBalancedTreeWithSortedKeysChecked, UnmarshalBinaryTree, and Decode return ErrTreeFull.
+// Trees derived from a limited tree, such as by Rekey or Union, are not limited.  A limit of 0 sets none.
+func WithInsertLimit(limit uint) LockingTreeOption {
+	return func(lt *LockingTree) {
+		lt.insertLimit = limit
+	}
+}
@@ -0,0 +1,89 @@
+package gerbst
+
+import (
+	"strings"
+)
+
+// treeGlyphs are the strings used to draw the branches of a printed tree.  Each must be the same width.
+type treeGlyphs struct {
+	empty     string
+	middle    string
+	continue_ string
+	last      string
+}
+
+var (
+	// unicodeGlyphs matches the glyphs used by gotree
+	unicodeGlyphs = treeGlyphs{
+		empty:     "    ",
+		middle:    "├── ",
+		continue_: "│   ",
+		last:      "└── ",
+	}
+
+	// asciiGlyphs are safe for output that mangles box-drawing characters
+	asciiGlyphs = treeGlyphs{
+		empty:     "    ",
+		middle:    "|-- ",
+		continue_: "|   ",
+		last:      "+-- ",
+	}
+)
+
+// printTree renders the subtree beneath root using the provided glyphs, producing output byte-identical to printing
+// the gotree.Tree returned by buildTreePrinter.  It uses an explicit stack rather than recursion so that arbitrarily
+// deep trees may be printed.
+func printTree(root *treeNode, label func(*treeNode) string, glyphs treeGlyphs) string {
+	type frame struct {
+		tn    *treeNode
+		depth int
+		last  bool // whether tn is the last branch of its parent
+	}
+
+	sb := new(strings.Builder)
+	sb.WriteString(label(root))
+	sb.WriteString("\n")
+
+	// spaces[i] records whether the ancestor at depth i+1 of the node being printed was the last branch of its own
+	// parent, which determines the glyphs drawn in that column
+	spaces := make([]bool, 0)
+	stack := make([]frame, 0)
+	push := func(tn *treeNode, depth int) {
+		// push right first so left is printed first
+		if tn.right != nil {
+			stack = append(stack, frame{tn: tn.right, depth: depth, last: true})
+		}
+		if tn.left != nil {
+			stack = append(stack, frame{tn: tn.left, depth: depth, last: tn.right == nil})
+		}
+	}
+	push(root, 1)
+
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		spaces = spaces[:f.depth-1]
+
+		last := true
+		for _, space := range spaces {
+			if space {
+				sb.WriteString(glyphs.empty)
+			} else {
+				sb.WriteString(glyphs.continue_)
+			}
+			last = space
+		}
+		if last {
+			sb.WriteString(glyphs.last)
+		} else {
+			sb.WriteString(glyphs.middle)
+		}
+		sb.WriteString(label(f.tn))
+		sb.WriteString("\n")
+
+		spaces = append(spaces, f.last)
+		push(f.tn, f.depth+1)
+	}
+
+	return sb.String()
+}
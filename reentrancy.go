@@ -0,0 +1,56 @@
+package gerbst
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// goroutineID returns the id of the calling goroutine, parsed from the header of its stack trace.  This is slow, and
+// is used only by trees constructed WithReentrancyDetection.
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// the trace begins with "goroutine 123 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseInt(string(buf), 10, 64)
+	return id
+}
+
+// rlock acquires the read lock, first panicking if reentrancy detection is enabled and the calling goroutine is
+// running a callback while holding the write lock, which would otherwise deadlock
+func (n *LockingTree) rlock() {
+	n.checkReentrant()
+	n.mu.RLock()
+}
+
+// lock acquires the write lock, with the same check as rlock
+func (n *LockingTree) lock() {
+	n.checkReentrant()
+	n.mu.Lock()
+}
+
+func (n *LockingTree) checkReentrant() {
+	if !n.reentrancy {
+		return
+	}
+	if owner := atomic.LoadInt64(&n.callbackOwner); owner != 0 && owner == goroutineID() {
+		panic("gerbst: tree accessed from within a callback holding its write lock, which would deadlock")
+	}
+}
+
+// inCallback runs fn, which calls user code while the write lock is held, recording the calling goroutine so that
+// reentrant calls may be detected
+func (n *LockingTree) inCallback(fn func()) {
+	if !n.reentrancy {
+		fn()
+		return
+	}
+	atomic.StoreInt64(&n.callbackOwner, goroutineID())
+	defer atomic.StoreInt64(&n.callbackOwner, 0)
+	fn()
+}
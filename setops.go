@@ -0,0 +1,160 @@
+package gerbst
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Intersect returns a new, balanced tree containing only the keys present in both this tree and other, with values
+// taken from this tree.  Each tree is snapshotted under its own read lock, and the two ascending sequences are then
+// merged in O(n+m).  The keys of other are first passed through this tree's key normalizer, see WithKeyNormalizer.
+// The new tree is configured with the same options as this tree.  Neither tree is modified.
+func (n *LockingTree) Intersect(other *LockingTree) *LockingTree {
+	a, b := n.snapshot(), n.normalizeNodes(other.snapshot())
+	out := make([]*Node, 0)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		if a[i].key < b[j].key {
+			i++
+		} else if a[i].key > b[j].key {
+			j++
+		} else {
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return newBalancedLockingTree(n.derive(), out)
+}
+
+// Union returns a new, balanced tree containing every key present in either this tree or other.  Where a key is
+// present in both, the value is taken from this tree.  The keys of other are first passed through this tree's key
+// normalizer, see WithKeyNormalizer, and where several normalize to the same key the value of the lowest is taken.  The
+// new tree is configured with the same options as this tree.  Neither tree is modified.
+func (n *LockingTree) Union(other *LockingTree) *LockingTree {
+	a, b := n.snapshot(), n.normalizeNodes(other.snapshot())
+	out := make([]*Node, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i].key < b[j].key {
+			out = append(out, a[i])
+			i++
+		} else if a[i].key > b[j].key {
+			out = append(out, b[j])
+			j++
+		} else {
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	out = append(out, b[j:]...)
+	return newBalancedLockingTree(n.derive(), out)
+}
+
+// Difference returns a new, balanced tree containing only the keys present in this tree that are absent from other.
+// The keys of other are first passed through this tree's key normalizer, see WithKeyNormalizer.  The new tree is
+// configured with the same options as this tree.  Neither tree is modified.
+func (n *LockingTree) Difference(other *LockingTree) *LockingTree {
+	a, b := n.snapshot(), n.normalizeNodes(other.snapshot())
+	out := make([]*Node, 0, len(a))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i].key < b[j].key {
+			out = append(out, a[i])
+			i++
+		} else if a[i].key > b[j].key {
+			j++
+		} else {
+			i++
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return newBalancedLockingTree(n.derive(), out)
+}
+
+// EqualSet returns true if this tree and other hold the same keys with equal values, regardless of shape.  Values are
+// compared with ==, so must be comparable.
+func (n *LockingTree) EqualSet(other *LockingTree) bool {
+	a, b := n.snapshot(), other.snapshot()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key != b[i].key || a[i].value != b[i].value {
+			return false
+		}
+	}
+	return true
+}
+
+// Graft inserts every node of subtree into this tree, leaving subtree untouched.  Unlike Union it refuses overlap: if
+// any key of subtree is already present in this tree an error is returned and nothing is inserted, as is ErrTreeFull if
+// the nodes would not all fit within the limit set by WithInsertLimit.  Keys are passed through this tree's key
+// normalizer before they are checked for overlap, so two keys of subtree normalizing to the same key also overlap.
+// Nodes are inserted in subtree's pre-order so that, where they land beneath a single node, they keep their original
+// shape.
+func (n *LockingTree) Graft(subtree *LockingTree) error {
+	subtree.rlock()
+	nodes := make([]*Node, 0)
+	if subtree.root != nil {
+		subtree.root.walkPreOrder(func(tn *treeNode) bool {
+			nodes = append(nodes, tn.Node)
+			return true
+		})
+	}
+	subtree.mu.RUnlock()
+
+	keys := make([]uint, len(nodes))
+	seen := make(map[uint]uint, len(nodes))
+	for i, node := range nodes {
+		keys[i] = n.normalizeKey(node.key)
+		if prev, ok := seen[keys[i]]; ok {
+			return fmt.Errorf("unable to graft: keys %d and %d both normalize to %d", prev, node.key, keys[i])
+		}
+		seen[keys[i]] = node.key
+	}
+
+	n.lock()
+	defer n.mu.Unlock()
+	for _, key := range keys {
+		if n.root != nil && n.root.find(key) != nil {
+			return fmt.Errorf("unable to graft: key %d already exists", key)
+		}
+	}
+	if n.insertLimit > 0 {
+		var count uint
+		if n.root != nil {
+			count = n.root.count
+		}
+		if count+uint(len(nodes)) > n.insertLimit {
+			return fmt.Errorf("unable to graft %d keys: %w", len(nodes), ErrTreeFull)
+		}
+	}
+	for i, node := range nodes {
+		n.put(keys[i], node.value, false)
+	}
+	return nil
+}
+
+// normalizeNodes passes the keys of nodes, taken in ascending key order from another tree, through this tree's key
+// normalizer, returning them in ascending order of their normalized keys.  Where several normalize to the same key,
+// only the node with the lowest original key is kept.  The nodes are returned as they are if there is no normalizer.
+func (n *LockingTree) normalizeNodes(nodes []*Node) []*Node {
+	if n == nil || n.keyNormalizer == nil {
+		return nodes
+	}
+	out := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		out = append(out, newNode(n.keyNormalizer(node.key), node.value, node.depth, node.side))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].key < out[j].key })
+	kept := out[:0]
+	for i, node := range out {
+		if i == 0 || node.key != kept[len(kept)-1].key {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,294 @@
+package gerbst_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestLockingTree_Intersect(t *testing.T) {
+	a := gerbst.NewLockingTreeWithKeysValue([]uint{12, 11, 90, 82, 7, 9}, "a")
+	b := gerbst.NewLockingTreeWithKeysValue([]uint{1, 9, 12, 50, 82, 100}, "b")
+
+	in := a.Intersect(b)
+	if err := in.SelfCheck(); err != nil {
+		t.Logf("Expected intersection to pass self check, saw %v", err)
+		t.Fail()
+	}
+	if c := in.Count(); c != 3 {
+		t.Logf("Expected intersection to hold 3 keys, saw %d", c)
+		t.Fail()
+	}
+	for _, k := range []uint{9, 12, 82} {
+		if n, ok := in.Get(k); !ok {
+			t.Logf("Expected intersection to contain key %d", k)
+			t.Fail()
+		} else if v := n.Value(); v != "a" {
+			t.Logf("Expected key %d to take the receiver's value, saw %v", k, v)
+			t.Fail()
+		}
+	}
+
+	if c := a.Intersect(gerbst.NewLockingTree()).Count(); c != 0 {
+		t.Logf("Expected intersection with an empty tree to be empty, saw %d", c)
+		t.Fail()
+	}
+	if c := a.Intersect(a).Count(); c != a.Count() {
+		t.Logf("Expected self intersection to hold %d keys, saw %d", a.Count(), c)
+		t.Fail()
+	}
+	if c := a.Count(); c != 6 {
+		t.Logf("Expected receiver to be untouched, saw count %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_UnionDifference(t *testing.T) {
+	aKeys := []uint{12, 11, 90, 82, 7, 9}
+	bKeys := []uint{1, 9, 12, 50, 82, 100, 101}
+	a := gerbst.NewLockingTreeWithKeysValue(aKeys, "a")
+	b := gerbst.NewLockingTreeWithKeysValue(bKeys, "b")
+
+	union := a.Union(b)
+	inter := a.Intersect(b)
+	diffAB := a.Difference(b)
+	diffBA := b.Difference(a)
+
+	for name, lt := range map[string]*gerbst.LockingTree{"union": union, "a-b": diffAB, "b-a": diffBA} {
+		if err := lt.SelfCheck(); err != nil {
+			t.Logf("Expected %s to pass self check, saw %v", name, err)
+			t.Fail()
+		}
+	}
+
+	// |A ∪ B| = |A| + |B| - |A ∩ B|
+	if c, expected := union.Count(), a.Count()+b.Count()-inter.Count(); c != expected || c != 10 {
+		t.Logf("Expected union to hold %d keys, saw %d", expected, c)
+		t.Fail()
+	}
+	// |A - B| = |A| - |A ∩ B|
+	if c, expected := diffAB.Count(), a.Count()-inter.Count(); c != expected || c != 3 {
+		t.Logf("Expected A-B to hold %d keys, saw %d", expected, c)
+		t.Fail()
+	}
+	// |A ∪ B| = |A - B| + |B - A| + |A ∩ B|
+	if c := diffAB.Count() + diffBA.Count() + inter.Count(); c != union.Count() {
+		t.Logf("Expected differences and intersection to partition the union, saw %d vs %d", c, union.Count())
+		t.Fail()
+	}
+
+	for _, k := range aKeys {
+		if n, ok := union.Get(k); !ok || n.Value() != "a" {
+			t.Logf("Expected union key %d to take the receiver's value", k)
+			t.Fail()
+		}
+	}
+	for _, k := range []uint{1, 50, 100, 101} {
+		if n, ok := union.Get(k); !ok || n.Value() != "b" {
+			t.Logf("Expected union key %d to take the other tree's value", k)
+			t.Fail()
+		}
+	}
+	for _, k := range []uint{7, 11, 90} {
+		if !diffAB.Contains(k) {
+			t.Logf("Expected A-B to contain key %d", k)
+			t.Fail()
+		}
+	}
+
+	if d := union.DepthMax(); d != 4 {
+		t.Logf("Expected balanced union of 10 keys to have max depth 4, saw %d", d)
+		t.Fail()
+	}
+	if c := a.Count(); c != 6 {
+		t.Logf("Expected receiver to be untouched, saw count %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_SetOpsOptions(t *testing.T) {
+	a := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}, gerbst.WithDepthBase(gerbst.DepthBaseZero))
+	b := gerbst.NewLockingTreeWithKeys([]uint{1, 9, 12, 50})
+
+	rootDepth := func(lt *gerbst.LockingTree) uint {
+		for _, fn := range lt.Flatten() {
+			if !fn.HasParent {
+				return fn.Depth
+			}
+		}
+		return math.MaxUint
+	}
+
+	for name, lt := range map[string]*gerbst.LockingTree{"union": a.Union(b), "intersect": a.Intersect(b), "a-b": a.Difference(b)} {
+		if d := rootDepth(lt); d != 0 {
+			t.Logf("Expected %s to keep the receiver's depth base, saw root depth %d", name, d)
+			t.Fail()
+		}
+	}
+	if d := rootDepth(b.Union(a)); d != 1 {
+		t.Logf("Expected union to take the depth base of its receiver, saw root depth %d", d)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_SetOpsInsertLimit(t *testing.T) {
+	a := gerbst.NewLockingTreeWithKeys([]uint{1, 2}, gerbst.WithInsertLimit(2))
+	b := gerbst.NewLockingTreeWithKeys([]uint{3, 4})
+
+	for name, lt := range map[string]*gerbst.LockingTree{"union": a.Union(b), "intersect": a.Intersect(b), "a-b": a.Difference(b)} {
+		if l := lt.InsertLimit(); l != 0 {
+			t.Logf("Expected %s not to inherit the insert limit, saw %d", name, l)
+			t.Fail()
+		}
+		if err := lt.PutErr(5, 5); err != nil {
+			t.Logf("Expected %s to accept new keys, saw %v", name, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_SetOpsNormalized(t *testing.T) {
+	bucket := func(k uint) uint { return k &^ 7 }
+	a := gerbst.NewLockingTreeWithKeysValue([]uint{8, 16}, "a", gerbst.WithKeyNormalizer(bucket))
+	b := gerbst.NewLockingTree()
+	for _, k := range []uint{13, 25, 30} {
+		b.Put(k, k)
+	}
+
+	union := a.Union(b)
+	if s := fmt.Sprint(union.Keys()); s != "[8 16 24]" {
+		t.Logf("Expected union keys [8 16 24], saw %s", s)
+		t.Fail()
+	}
+	if n, ok := union.Get(13); !ok || n.Key() != 8 || n.Value() != "a" {
+		t.Logf("Expected union to keep the receiver's value for bucket 8, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+	if n, ok := union.Get(31); !ok || n.Value() != uint(25) {
+		t.Logf("Expected union bucket 24 to take the value of the lowest key 25, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+	if err := union.SelfCheck(); err != nil {
+		t.Logf("Expected union to pass self check, saw %v", err)
+		t.Fail()
+	}
+	if s := fmt.Sprint(a.Intersect(b).Keys()); s != "[8]" {
+		t.Logf("Expected intersection keys [8], saw %s", s)
+		t.Fail()
+	}
+	if s := fmt.Sprint(a.Difference(b).Keys()); s != "[16]" {
+		t.Logf("Expected difference keys [16], saw %s", s)
+		t.Fail()
+	}
+
+	rk, err := a.Rekey(func(old uint) uint { return old + 5 })
+	if err != nil {
+		t.Logf("Unexpected error rekeying: %v", err)
+		t.FailNow()
+	}
+	if s := fmt.Sprint(rk.Keys()); s != "[8 16]" {
+		t.Logf("Expected rekeyed keys to be normalized to [8 16], saw %s", s)
+		t.Fail()
+	}
+	if _, err := a.Rekey(func(old uint) uint { return old / 4 }); err == nil {
+		t.Log("Expected error rekeying two keys into the same bucket")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_EqualSet(t *testing.T) {
+	a := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	b := gerbst.NewLockingTreeWithKeys([]uint{7, 9, 11, 12, 82, 90})
+
+	if !a.EqualSet(b) {
+		t.Log("Expected differently shaped trees with the same keys to be set-equal")
+		t.Fail()
+	}
+
+	b.Put(9, "nine")
+	if a.EqualSet(b) {
+		t.Log("Expected trees with differing values not to be set-equal")
+		t.Fail()
+	}
+
+	b.Put(9, uint(9))
+	b.Put(100, uint(100))
+	if a.EqualSet(b) {
+		t.Log("Expected trees with differing keys not to be set-equal")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Graft(t *testing.T) {
+	t.Run("disjoint", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90})
+		sub := gerbst.NewLockingTreeWithKeys([]uint{82, 7, 9})
+		if err := lt.Graft(sub); err != nil {
+			t.Logf("Unexpected error grafting disjoint subtree: %v", err)
+			t.FailNow()
+		}
+		if !lt.EqualSet(gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})) {
+			t.Logf("Expected grafted tree to contain both key sets, saw %v", lt.Keys())
+			t.Fail()
+		}
+		if err := lt.SelfCheck(); err != nil {
+			t.Logf("Expected grafted tree to pass self check, saw %v", err)
+			t.Fail()
+		}
+		if c := sub.Count(); c != 3 {
+			t.Logf("Expected subtree to be untouched, saw count %d", c)
+			t.Fail()
+		}
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90})
+		sub := gerbst.NewLockingTreeWithKeys([]uint{82, 7, 11, 9})
+		if err := lt.Graft(sub); err == nil {
+			t.Log("Expected error grafting overlapping subtree")
+			t.Fail()
+		}
+		if c := lt.Count(); c != 3 || lt.ContainsAny([]uint{82, 7, 9}) {
+			t.Logf("Expected failed graft not to insert anything, saw keys %v", lt.Keys())
+			t.Fail()
+		}
+	})
+	t.Run("normalized", func(t *testing.T) {
+		bucket := func(k uint) uint { return k &^ 7 }
+		lt := gerbst.NewLockingTreeWithKeys([]uint{8, 16}, gerbst.WithKeyNormalizer(bucket))
+
+		// 13 normalizes to the existing bucket 8
+		if err := lt.Graft(gerbst.NewLockingTreeWithKeys([]uint{13, 30})); err == nil {
+			t.Log("Expected error grafting a key normalizing to a present key")
+			t.Fail()
+		}
+		// 25 and 30 normalize to the same bucket 24
+		if err := lt.Graft(gerbst.NewLockingTreeWithKeys([]uint{25, 30})); err == nil {
+			t.Log("Expected error grafting two keys normalizing to the same key")
+			t.Fail()
+		}
+		if s := fmt.Sprint(lt.Keys()); s != "[8 16]" {
+			t.Logf("Expected failed grafts not to insert anything, saw keys %s", s)
+			t.Fail()
+		}
+
+		if err := lt.Graft(gerbst.NewLockingTreeWithKeys([]uint{30, 35})); err != nil {
+			t.Logf("Unexpected error grafting keys absent once normalized: %v", err)
+			t.FailNow()
+		}
+		if s := fmt.Sprint(lt.Keys()); s != "[8 16 24 32]" {
+			t.Logf("Expected grafted keys to be normalized, saw keys %s", s)
+			t.Fail()
+		}
+		if n, ok := lt.Get(30); !ok || n.Key() != 24 || n.Value() != uint(30) {
+			t.Logf("Expected Get(30) to find bucket 24 holding 30, saw %v (%t)", n, ok)
+			t.Fail()
+		}
+		if err := lt.SelfCheck(); err != nil {
+			t.Logf("Expected grafted tree to pass self check, saw %v", err)
+			t.Fail()
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package gerbst
+
+import (
+	"sync"
+)
+
+// splayNode is the internal representation of a node within a SplayTree.  Unlike treeNode it carries no aggregate
+// metadata, as every access restructures the path to the root and would invalidate it.
+type splayNode struct {
+	id    uint64
+	key   uint
+	value interface{}
+
+	parent *splayNode
+	left   *splayNode
+	right  *splayNode
+}
+
+// SplayTree is a self-adjusting binary search tree that moves each key accessed by Get or Put to the root, so that
+// recently accessed keys are found quickly.  Operations take amortized O(log n) time.
+//
+// As Get restructures the tree, every method takes an exclusive lock.
+type SplayTree struct {
+	mu sync.Mutex
+
+	root *splayNode
+
+	count uint
+}
+
+// NewSplayTree constructs a new, empty splay tree
+func NewSplayTree() *SplayTree {
+	st := new(SplayTree)
+	return st
+}
+
+// Count returns the number of keys within this tree
+func (st *SplayTree) Count() uint {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.count
+}
+
+// Get attempts to retrieve a node by key, moving it to the root if found.  If the key is absent, the last node visited
+// during the search is moved to the root instead.  As a found node is always the root, the returned node reports a
+// depth of 1.
+func (st *SplayTree) Get(key uint) (*Node, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.root == nil {
+		return nil, false
+	}
+	sn, found := st.search(key)
+	st.splay(sn)
+	if !found {
+		return nil, false
+	}
+	return &Node{id: sn.id, key: sn.key, value: sn.value, depth: 1, side: NodeSideRoot}, true
+}
+
+// Put inserts a new node or updates the value of an existing node, moving it to the root
+func (st *SplayTree) Put(key uint, value interface{}) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.root == nil {
+		st.root = &splayNode{id: nextNodeID(), key: key, value: value}
+		st.count++
+		return
+	}
+	sn, found := st.search(key)
+	if found {
+		sn.value = value
+	} else {
+		child := &splayNode{id: nextNodeID(), key: key, value: value, parent: sn}
+		if key < sn.key {
+			sn.left = child
+		} else {
+			sn.right = child
+		}
+		sn = child
+		st.count++
+	}
+	st.splay(sn)
+}
+
+// Depth returns the depth of the node with the provided key, with the root at depth 1, or false if the key is absent.
+// Unlike Get it does not restructure the tree.
+func (st *SplayTree) Depth(key uint) (uint, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var depth uint
+	for sn := st.root; sn != nil; {
+		depth++
+		if key == sn.key {
+			return depth, true
+		} else if key < sn.key {
+			sn = sn.left
+		} else {
+			sn = sn.right
+		}
+	}
+	return 0, false
+}
+
+// Keys returns every key in this tree in ascending order.  It does not restructure the tree.
+func (st *SplayTree) Keys() []uint {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	keys := make([]uint, 0, st.count)
+	stack := make([]*splayNode, 0)
+	for sn := st.root; sn != nil || len(stack) > 0; {
+		for ; sn != nil; sn = sn.left {
+			stack = append(stack, sn)
+		}
+		sn = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		keys = append(keys, sn.key)
+		sn = sn.right
+	}
+	return keys
+}
+
+// search returns the node holding key and true, or the node beneath which key would be inserted and false.  The tree
+// must not be empty.
+func (st *SplayTree) search(key uint) (*splayNode, bool) {
+	sn := st.root
+	for {
+		var next *splayNode
+		if key == sn.key {
+			return sn, true
+		} else if key < sn.key {
+			next = sn.left
+		} else {
+			next = sn.right
+		}
+		if next == nil {
+			return sn, false
+		}
+		sn = next
+	}
+}
+
+// splay moves sn to the root through a series of zig, zig-zig, and zig-zag rotations
+func (st *SplayTree) splay(sn *splayNode) {
+	for sn.parent != nil {
+		p := sn.parent
+		g := p.parent
+		if g == nil {
+			// zig
+			st.rotate(sn)
+		} else if (g.left == p) == (p.left == sn) {
+			// zig-zig
+			st.rotate(p)
+			st.rotate(sn)
+		} else {
+			// zig-zag
+			st.rotate(sn)
+			st.rotate(sn)
+		}
+	}
+}
+
+// rotate moves sn above its parent, preserving key order
+func (st *SplayTree) rotate(sn *splayNode) {
+	p := sn.parent
+	g := p.parent
+	if p.left == sn {
+		p.left = sn.right
+		if sn.right != nil {
+			sn.right.parent = p
+		}
+		sn.right = p
+	} else {
+		p.right = sn.left
+		if sn.left != nil {
+			sn.left.parent = p
+		}
+		sn.left = p
+	}
+	p.parent = sn
+	sn.parent = g
+	if g == nil {
+		st.root = sn
+	} else if g.left == p {
+		g.left = sn
+	} else {
+		g.right = sn
+	}
+}
@@ -0,0 +1,66 @@
+package gerbst_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestSplayTree(t *testing.T) {
+	st := gerbst.NewSplayTree()
+	keys := rand.New(rand.NewSource(160)).Perm(200)
+	for _, k := range keys {
+		st.Put(uint(k), k)
+	}
+	st.Put(7, "seven")
+
+	if c := st.Count(); c != uint(len(keys)) {
+		t.Logf("Expected duplicate put not to increment count, saw %d", c)
+		t.Fail()
+	}
+
+	for i := 0; i < 5; i++ {
+		n, ok := st.Get(42)
+		if !ok {
+			t.Log("Expected key 42 to be found")
+			t.FailNow()
+		}
+		if n.Depth() != 1 || !n.Side().IsRoot() {
+			t.Logf("Expected accessed key to be returned as the root, saw depth %d side %s", n.Depth(), n.Side())
+			t.Fail()
+		}
+		if d, _ := st.Depth(42); d != 1 {
+			t.Logf("Expected accessed key to be at depth 1, saw %d", d)
+			t.Fail()
+		}
+	}
+
+	if n, ok := st.Get(7); !ok || n.Value() != "seven" {
+		t.Logf("Expected key 7 to hold updated value, saw %v (%t)", n, ok)
+		t.Fail()
+	}
+	if _, ok := st.Get(1000); ok {
+		t.Log("Expected absent key not to be found")
+		t.Fail()
+	}
+	// the search for 1000 ends at the highest key, which is splayed to the root
+	if d, _ := st.Depth(199); d != 1 {
+		t.Logf("Expected miss to splay the last visited key 199 to the root, saw depth %d", d)
+		t.Fail()
+	}
+
+	got := st.Keys()
+	if len(got) != len(keys) || !sort.SliceIsSorted(got, func(i, j int) bool { return got[i] < got[j] }) {
+		t.Logf("Expected tree to remain a valid binary search tree, saw in-order keys %v", got)
+		t.Fail()
+	}
+	for i, k := range got {
+		if k != uint(i) {
+			t.Logf("Expected key %d at index %d, saw %d", i, i, k)
+			t.Fail()
+			break
+		}
+	}
+}
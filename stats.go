@@ -0,0 +1,299 @@
+package gerbst
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LongestChain returns the longest run of nodes in which each node but the last has exactly one child, the last
+// being the sole child of its predecessor.  Such runs are degenerate, list-like stretches of the tree that would
+// benefit from rebalancing.  Returns nil if no node has exactly one child.
+func (n *LockingTree) LongestChain() []*Node {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+
+	var (
+		best    *treeNode
+		bestLen uint
+	)
+
+	// chain returns the number of single-child links starting at tn, recording the longest seen
+	var chain func(tn *treeNode) uint
+	chain = func(tn *treeNode) uint {
+		var l, r uint
+		if tn.left != nil {
+			l = chain(tn.left)
+		}
+		if tn.right != nil {
+			r = chain(tn.right)
+		}
+		var length uint
+		if tn.left != nil && tn.right == nil {
+			length = l + 1
+		} else if tn.right != nil && tn.left == nil {
+			length = r + 1
+		}
+		if length > bestLen {
+			best = tn
+			bestLen = length
+		}
+		return length
+	}
+	chain(n.root)
+
+	if best == nil {
+		return nil
+	}
+	out := make([]*Node, 0, bestLen+1)
+	for tn := best; ; tn = tn.onlyChild() {
+		out = append(out, tn.Node)
+		if uint(len(out)) > bestLen {
+			break
+		}
+	}
+	return out
+}
+
+// InternalPathLength returns the sum of the depths of every node in this tree, as reported by Node.Depth, so it is
+// affected by WithDepthBase.  Lower is better balanced for the same keys.
+func (n *LockingTree) InternalPathLength() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	return n.root.pathLength()
+}
+
+// AverageDepth returns the mean depth of the nodes in this tree, or 0 if it is empty.  This is InternalPathLength
+// divided by Count.
+func (n *LockingTree) AverageDepth() float64 {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	return float64(n.root.pathLength()) / float64(n.root.count)
+}
+
+// LeafCount returns the number of nodes in this tree without any children
+func (n *LockingTree) LeafCount() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	var c uint
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		if tn.left == nil && tn.right == nil {
+			c++
+		}
+		return true
+	})
+	return c
+}
+
+// RootToLeafPaths returns, for each leaf from left to right, the keys of every node from the root down to and
+// including that leaf.  The number of paths is equal to LeafCount.
+func (n *LockingTree) RootToLeafPaths() [][]uint {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+	var (
+		paths [][]uint
+		path  = make([]uint, 0, n.root.depthMax-n.root.depth+1)
+	)
+	n.root.walkInOut(
+		func(tn *treeNode) bool {
+			path = append(path, tn.key)
+			if tn.left == nil && tn.right == nil {
+				paths = append(paths, append([]uint(nil), path...))
+			}
+			return true
+		},
+		func(*treeNode) {
+			path = path[:len(path)-1]
+		})
+	return paths
+}
+
+// IsBalanced returns true if, at every node in this tree, the heights of the left and right branches differ by at most
+// one.  An empty tree is balanced.  This is O(n).
+func (n *LockingTree) IsBalanced() bool {
+	if n == nil {
+		return true
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return true
+	}
+	return n.root.heightBalanced()
+}
+
+// RotationsToBalance returns the number of single rotations BalanceDSW would perform to balance this tree in place, or 0
+// if it is already balanced as reported by IsBalanced.  The count is exact: one right rotation for each node not on the
+// root's rightmost path, to flatten the tree into a sorted vine, followed by the left rotations that compress the vine
+// into a balanced tree, which depend only on Count.
+func (n *LockingTree) RotationsToBalance() uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil || n.root.heightBalanced() {
+		return 0
+	}
+	return dswRotations(n.root.count, n.root.spineLength())
+}
+
+// Shape returns a canonical representation of this tree's branching structure alone, ignoring keys and values, such
+// that two trees share a Shape exactly when they share a shape.  Each node is written as a pair of parentheses
+// enclosing its left then right branch, with a leaf written as "()", and a "-" standing in for an absent branch beside
+// a present one.  A root with two leaf children is therefore "(()())", and a root with only a right leaf is "(-())".
+// An empty tree returns an empty string.
+func (n *LockingTree) Shape() string {
+	if n == nil {
+		return ""
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return ""
+	}
+	sb := new(strings.Builder)
+	n.root.walkInOut(
+		func(tn *treeNode) bool {
+			if tn.side == NodeSideRight && tn.parent.left == nil {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte('(')
+			return true
+		},
+		func(tn *treeNode) {
+			if tn.side == NodeSideLeft && tn.parent.right == nil {
+				sb.WriteString(")-")
+			} else {
+				sb.WriteByte(')')
+			}
+		})
+	return sb.String()
+}
+
+// DebugDump returns the full internal metadata of every node in this tree, one line per node in pre-order, for use
+// when diagnosing metadata corruption.  The format is meant for humans and may change.
+func (n *LockingTree) DebugDump() string {
+	if n == nil {
+		return ""
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return ""
+	}
+	sb := new(strings.Builder)
+	n.root.walkPreOrder(func(tn *treeNode) bool {
+		sb.WriteString(tn.metaString())
+		sb.WriteString("\n")
+		return true
+	})
+	return sb.String()
+}
+
+// uintValues calls fn with the value of every node in ascending key order, returning an error upon the first value
+// that is not a uint.  Expects the caller to hold the read lock.
+func (n *LockingTree) uintValues(fn func(v uint)) error {
+	if n.root == nil {
+		return nil
+	}
+	var err error
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		v, ok := tn.value.(uint)
+		if !ok {
+			err = fmt.Errorf("value for key %d must be uint, saw %T", tn.key, tn.value)
+			return false
+		}
+		fn(v)
+		return true
+	})
+	return err
+}
+
+// SumUintValues returns the sum of the values of every node, returning an error if any value is not a uint.  The sum
+// of an empty tree is 0.  Overflow wraps, as with any uint addition.
+func (n *LockingTree) SumUintValues() (uint, error) {
+	if n == nil {
+		return 0, nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	var sum uint
+	if err := n.uintValues(func(v uint) { sum += v }); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+// MinUintValue returns the lowest value of any node, returning an error if any value is not a uint or if this tree is
+// empty
+func (n *LockingTree) MinUintValue() (uint, error) {
+	if n == nil {
+		return 0, ErrEmptyTree
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0, ErrEmptyTree
+	}
+	min := ^uint(0)
+	if err := n.uintValues(func(v uint) {
+		if v < min {
+			min = v
+		}
+	}); err != nil {
+		return 0, err
+	}
+	return min, nil
+}
+
+// MaxUintValue returns the highest value of any node, returning an error if any value is not a uint or if this tree
+// is empty
+func (n *LockingTree) MaxUintValue() (uint, error) {
+	if n == nil {
+		return 0, ErrEmptyTree
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0, ErrEmptyTree
+	}
+	var max uint
+	if err := n.uintValues(func(v uint) {
+		if v > max {
+			max = v
+		}
+	}); err != nil {
+		return 0, err
+	}
+	return max, nil
+}
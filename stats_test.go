@@ -0,0 +1,275 @@
+package gerbst_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func nodeKeys(nodes []*gerbst.Node) []uint {
+	keys := make([]uint, len(nodes))
+	for i, n := range nodes {
+		keys[i] = n.Key()
+	}
+	return keys
+}
+
+func equalKeys(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLockingTree_LongestChain(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []uint
+		chain []uint
+	}{
+		{name: "empty", keys: nil, chain: []uint{}},
+		{name: "balanced", keys: []uint{4, 2, 6, 1, 3, 5, 7}, chain: []uint{}},
+		{name: "sample", keys: []uint{12, 11, 90, 82, 7, 9}, chain: []uint{11, 7, 9}},
+		{name: "lopsided", keys: []uint{10, 5, 20, 30, 40, 35, 45}, chain: []uint{20, 30, 40}},
+		{name: "degenerate", keys: []uint{10, 5, 20, 30, 40, 50}, chain: []uint{20, 30, 40, 50}},
+	}
+
+	for _, tt := range tests {
+		if chain := nodeKeys(gerbst.NewLockingTreeWithKeys(tt.keys).LongestChain()); !equalKeys(chain, tt.chain) {
+			t.Logf("Expected %s tree to have longest chain %v, saw %v", tt.name, tt.chain, chain)
+			t.Fail()
+		}
+	}
+}
+
+func TestLockingTree_DebugDump(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	lines := strings.Split(strings.TrimSuffix(lt.DebugDump(), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Logf("Expected 6 lines, saw %d", len(lines))
+		t.FailNow()
+	}
+
+	expected := []string{
+		`key=12; .* side="ROOT", count=6; countLeft=3; countRight=2; depth=1; depthMax=4; depthMaxLeft=4; depthMaxRight=3; loKey=7; hiKey=90$`,
+		`key=11; .* side="LEFT", count=3; countLeft=2; countRight=0; depth=2; depthMax=4; depthMaxLeft=4; depthMaxRight=0; loKey=7; hiKey=11$`,
+		`key=7; .* side="LEFT", count=2; countLeft=0; countRight=1; depth=3; depthMax=4; depthMaxLeft=0; depthMaxRight=4; loKey=7; hiKey=9$`,
+		`key=9; .* side="RIGHT", count=1; countLeft=0; countRight=0; depth=4; depthMax=4; depthMaxLeft=0; depthMaxRight=0; loKey=9; hiKey=9$`,
+		`key=90; .* side="RIGHT", count=2; countLeft=1; countRight=0; depth=2; depthMax=3; depthMaxLeft=3; depthMaxRight=0; loKey=82; hiKey=90$`,
+		`key=82; .* side="LEFT", count=1; countLeft=0; countRight=0; depth=3; depthMax=3; depthMaxLeft=0; depthMaxRight=0; loKey=82; hiKey=82$`,
+	}
+	for i, line := range lines {
+		if !regexp.MustCompile(`^` + expected[i]).MatchString(line) {
+			t.Logf("Expected line %d to match %q, saw %q", i, expected[i], line)
+			t.Fail()
+		}
+	}
+
+	if d := gerbst.NewLockingTree().DebugDump(); d != "" {
+		t.Logf("Expected empty dump for empty tree, saw %q", d)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_UintValueAggregates(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	if sum, err := lt.SumUintValues(); err != nil || sum != 211 {
+		t.Logf("Expected sum 211, saw %d (%v)", sum, err)
+		t.Fail()
+	}
+	if min, err := lt.MinUintValue(); err != nil || min != 7 {
+		t.Logf("Expected min 7, saw %d (%v)", min, err)
+		t.Fail()
+	}
+	if max, err := lt.MaxUintValue(); err != nil || max != 90 {
+		t.Logf("Expected max 90, saw %d (%v)", max, err)
+		t.Fail()
+	}
+
+	empty := gerbst.NewLockingTree()
+	if sum, err := empty.SumUintValues(); err != nil || sum != 0 {
+		t.Logf("Expected empty sum 0, saw %d (%v)", sum, err)
+		t.Fail()
+	}
+	if _, err := empty.MinUintValue(); !errors.Is(err, gerbst.ErrEmptyTree) {
+		t.Logf("Expected ErrEmptyTree for empty min, saw %v", err)
+		t.Fail()
+	}
+
+	lt.Put(50, "fifty")
+	if _, err := lt.SumUintValues(); err == nil {
+		t.Log("Expected error summing a non-uint value")
+		t.Fail()
+	}
+	if _, err := lt.MaxUintValue(); err == nil {
+		t.Log("Expected error for max with a non-uint value")
+		t.Fail()
+	}
+}
+
+func TestLockingTree_RootToLeafPaths(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	paths := lt.RootToLeafPaths()
+	if s := fmt.Sprint(paths); s != "[[12 11 7 9] [12 90 82]]" {
+		t.Logf("Expected paths [[12 11 7 9] [12 90 82]], saw %s", s)
+		t.Fail()
+	}
+	if c := lt.LeafCount(); c != uint(len(paths)) {
+		t.Logf("Expected LeafCount to equal the %d paths, saw %d", len(paths), c)
+		t.Fail()
+	}
+
+	lt.Put(100, 100)
+	lt.Put(10, 10)
+	paths = lt.RootToLeafPaths()
+	if s := fmt.Sprint(paths); s != "[[12 11 7 9 10] [12 90 82] [12 90 100]]" {
+		t.Logf("Expected paths [[12 11 7 9 10] [12 90 82] [12 90 100]], saw %s", s)
+		t.Fail()
+	}
+	if c := lt.LeafCount(); c != 3 {
+		t.Logf("Expected 3 leaves, saw %d", c)
+		t.Fail()
+	}
+
+	if p := gerbst.NewLockingTreeWithKeys([]uint{5}).RootToLeafPaths(); fmt.Sprint(p) != "[[5]]" {
+		t.Logf("Expected a lone root to be its own path, saw %v", p)
+		t.Fail()
+	}
+	if p := gerbst.NewLockingTree().RootToLeafPaths(); len(p) != 0 {
+		t.Logf("Expected an empty tree to have no paths, saw %v", p)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_PathLength(t *testing.T) {
+	sample := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	if l := sample.InternalPathLength(); l != 15 {
+		t.Logf("Expected sample tree to have internal path length 15, saw %d", l)
+		t.Fail()
+	}
+	if d := sample.AverageDepth(); d != 2.5 {
+		t.Logf("Expected sample tree to have average depth 2.5, saw %f", d)
+		t.Fail()
+	}
+
+	keys := make([]uint, 127)
+	for i := range keys {
+		keys[i] = uint(i)
+	}
+	degenerate := gerbst.NewLockingTreeWithKeys(keys)
+	balanced := gerbst.NewBalancedTreeWithSortedKeys(keys)
+
+	// a chain of 127 has depths 1 through 127, a perfect tree of 127 has 2^(d-1) nodes at each depth d from 1 to 7
+	if l := degenerate.InternalPathLength(); l != 127*128/2 {
+		t.Logf("Expected degenerate internal path length %d, saw %d", 127*128/2, l)
+		t.Fail()
+	}
+	if l := balanced.InternalPathLength(); l != 1*1+2*2+3*4+4*8+5*16+6*32+7*64 {
+		t.Logf("Expected balanced internal path length %d, saw %d", 1*1+2*2+3*4+4*8+5*16+6*32+7*64, l)
+		t.Fail()
+	}
+	if bd, dd := balanced.AverageDepth(), degenerate.AverageDepth(); bd*5 > dd {
+		t.Logf("Expected balanced average depth %f to be markedly lower than degenerate %f", bd, dd)
+		t.Fail()
+	}
+
+	if d := gerbst.NewLockingTree().AverageDepth(); d != 0 {
+		t.Logf("Expected empty tree to have average depth 0, saw %f", d)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_RotationsToBalance(t *testing.T) {
+	ascending := make([]uint, 127)
+	descending := make([]uint, 127)
+	for i := range ascending {
+		ascending[i] = uint(i)
+		descending[i] = uint(126 - i)
+	}
+
+	tests := []struct {
+		name      string
+		lt        *gerbst.LockingTree
+		balanced  bool
+		rotations uint
+	}{
+		{name: "empty", lt: gerbst.NewLockingTree(), balanced: true},
+		{name: "single", lt: gerbst.NewLockingTreeWithKeys([]uint{5}), balanced: true},
+		{name: "perfect", lt: gerbst.NewLockingTreeWithKeys([]uint{4, 2, 6, 1, 3, 5, 7}), balanced: true},
+		{name: "incomplete", lt: gerbst.NewLockingTreeWithKeys([]uint{4, 2, 6, 1}), balanced: true},
+		// 4 right rotations bring 11, 7, 9, and 82 onto the spine, then 3 and 1 left rotations compress 6 nodes
+		{name: "sample", lt: gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9}), rotations: 8},
+		// already a vine, so only compression is needed: 63+31+15+7+3+1
+		{name: "ascending", lt: gerbst.NewLockingTreeWithKeys(ascending), rotations: 120},
+		// every node but the root must first be rotated onto the spine
+		{name: "descending", lt: gerbst.NewLockingTreeWithKeys(descending), rotations: 126 + 120},
+	}
+	for _, tt := range tests {
+		if b := tt.lt.IsBalanced(); b != tt.balanced {
+			t.Logf("Expected %s tree IsBalanced to be %t, saw %t", tt.name, tt.balanced, b)
+			t.Fail()
+		}
+		if r := tt.lt.RotationsToBalance(); r != tt.rotations {
+			t.Logf("Expected %s tree to need %d rotations, saw %d", tt.name, tt.rotations, r)
+			t.Fail()
+		}
+	}
+
+	lt := gerbst.NewLockingTreeWithKeys(ascending)
+	lt.Rebalance()
+	if !lt.IsBalanced() || lt.RotationsToBalance() != 0 {
+		t.Logf("Expected a rebalanced tree to need no rotations, saw %d", lt.RotationsToBalance())
+		t.Fail()
+	}
+}
+
+func TestLockingTree_Shape(t *testing.T) {
+	sample := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	if s := sample.Shape(); s != "(((-())-)(()-))" {
+		t.Logf("Expected sample tree to have shape (((-())-)(()-)), saw %s", s)
+		t.Fail()
+	}
+
+	shifted := gerbst.NewLockingTreeWithKeysValue([]uint{1012, 1011, 1090, 1082, 1007, 1009}, "v")
+	if a, b := sample.Shape(), shifted.Shape(); a != b {
+		t.Logf("Expected key-shifted tree to share shape %s, saw %s", a, b)
+		t.Fail()
+	}
+
+	tests := []struct {
+		keys  []uint
+		shape string
+	}{
+		{keys: nil, shape: ""},
+		{keys: []uint{5}, shape: "()"},
+		{keys: []uint{5, 3, 7}, shape: "(()())"},
+		{keys: []uint{5, 3}, shape: "(()-)"},
+		{keys: []uint{5, 7}, shape: "(-())"},
+		{keys: []uint{5, 7, 6}, shape: "(-(()-))"},
+		{keys: []uint{5, 7, 8}, shape: "(-(-()))"},
+	}
+	for _, tt := range tests {
+		if s := gerbst.NewLockingTreeWithKeys(tt.keys).Shape(); s != tt.shape {
+			t.Logf("Expected keys %v to have shape %s, saw %s", tt.keys, tt.shape, s)
+			t.Fail()
+		}
+	}
+
+	// mirrored single children must not collide
+	if a, b := gerbst.NewLockingTreeWithKeys([]uint{5, 3}).Shape(), gerbst.NewLockingTreeWithKeys([]uint{5, 7}).Shape(); a == b {
+		t.Logf("Expected mirrored trees to differ in shape, both saw %s", a)
+		t.Fail()
+	}
+}
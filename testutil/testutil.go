@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/dcarbone/gerbst"
@@ -121,3 +122,25 @@ func GetTestsFromKeys(existsKeys, missingKeys []uint) GetTests {
 	}
 	return gts
 }
+
+// BuildTestOrderIndependence inserts perms random permutations of keys into fresh trees, verifying that each is
+// set-equal to the tree built from keys in their given order.  The permutations are seeded so failures reproduce.
+func BuildTestOrderIndependence(keys []uint, p bool, perms int) func(*testing.T) {
+	return func(t *testing.T) {
+		if p {
+			t.Parallel()
+		}
+		expected := gerbst.NewLockingTreeWithKeys(keys)
+		rng := rand.New(rand.NewSource(int64(len(keys))))
+		for i := 0; i < perms; i++ {
+			perm := make([]uint, len(keys))
+			for j, k := range rng.Perm(len(keys)) {
+				perm[j] = keys[k]
+			}
+			if actual := gerbst.NewLockingTreeWithKeys(perm); !actual.EqualSet(expected) {
+				t.Logf("Expected tree built from permutation %v to be set-equal to tree built from %v", perm, keys)
+				t.Fail()
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gerbst
+
+// WalkInOut visits every node of this tree depth-first, left before right, calling enter as each node is descended
+// into (pre-order) and leave as it is ascended out of (post-order).  If enter returns false the node's branches are
+// skipped, though leave is still called for it, so every enter is always matched by exactly one leave.
+//
+// The read lock is held for the duration of the walk.
+func (n *LockingTree) WalkInOut(enter func(*Node) bool, leave func(*Node)) {
+	if n == nil {
+		return
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return
+	}
+	n.root.walkInOut(
+		func(tn *treeNode) bool { return enter(tn.Node) },
+		func(tn *treeNode) { leave(tn.Node) })
+}
+
+// CountFunc returns the number of nodes for which pred returns true, without materializing them.  pred is called in
+// ascending key order while the read lock is held.
+func (n *LockingTree) CountFunc(pred func(key uint, value interface{}) bool) uint {
+	if n == nil {
+		return 0
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return 0
+	}
+	var c uint
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		if pred(tn.key, tn.value) {
+			c++
+		}
+		return true
+	})
+	return c
+}
+
+// EachKey calls fn with each key of this tree in ascending order, halting when fn returns false.  fn is called while
+// the read lock is held.
+func (n *LockingTree) EachKey(fn func(key uint) bool) {
+	if n == nil {
+		return
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return
+	}
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		return fn(tn.key)
+	})
+}
+
+// Where returns every node for which pred returns true, in ascending key order.  It is the materializing counterpart to
+// CountFunc, and pred is likewise called in ascending key order while the read lock is held.
+func (n *LockingTree) Where(pred func(key uint, value interface{}) bool) []*Node {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+	var nodes []*Node
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		if pred(tn.key, tn.value) {
+			nodes = append(nodes, tn.Node)
+		}
+		return true
+	})
+	return nodes
+}
+
+// InOrderIndexed calls fn with each node of this tree in ascending key order alongside its zero-based in-order index,
+// which is also its rank, halting when fn returns false.  fn is called while the read lock is held.
+func (n *LockingTree) InOrderIndexed(fn func(index uint, node *Node) bool) {
+	if n == nil {
+		return
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return
+	}
+	var i uint
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		ok := fn(i, tn.Node)
+		i++
+		return ok
+	})
+}
+
+// DFS visits every node of this tree depth-first in pre-order, each node before its branches, halting when fn returns
+// false.  Left branches are visited before right unless rightFirst is set, which mirrors the visitation order.  fn is
+// called while the read lock is held.
+func (n *LockingTree) DFS(fn func(*Node) bool, rightFirst bool) {
+	if n == nil {
+		return
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return
+	}
+	visit := func(tn *treeNode) bool { return fn(tn.Node) }
+	if rightFirst {
+		n.root.walkPreOrderRightFirst(visit)
+	} else {
+		n.root.walkPreOrder(visit)
+	}
+}
@@ -0,0 +1,217 @@
+package gerbst_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestLockingTree_WalkInOut(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	record := func(skip uint) string {
+		var (
+			events []string
+			open   int
+		)
+		lt.WalkInOut(
+			func(n *gerbst.Node) bool {
+				open++
+				events = append(events, fmt.Sprintf("+%d", n.Key()))
+				return n.Key() != skip
+			},
+			func(n *gerbst.Node) {
+				open--
+				events = append(events, fmt.Sprintf("-%d", n.Key()))
+			})
+		if open != 0 {
+			t.Logf("Expected enter and leave calls to balance, saw %d unmatched", open)
+			t.Fail()
+		}
+		return strings.Join(events, " ")
+	}
+
+	if seq, expected := record(0), "+12 +11 +7 +9 -9 -7 -11 +90 +82 -82 -90 -12"; seq != expected {
+		t.Logf("Expected sequence %q, saw %q", expected, seq)
+		t.Fail()
+	}
+	if seq, expected := record(11), "+12 +11 -11 +90 +82 -82 -90 -12"; seq != expected {
+		t.Logf("Expected skipped sequence %q, saw %q", expected, seq)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_CountFunc(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	even := func(key uint, _ interface{}) bool { return key%2 == 0 }
+	if c := lt.CountFunc(even); c != 3 {
+		t.Logf("Expected 3 even keys, saw %d", c)
+		t.Fail()
+	}
+	if c := lt.CountFunc(func(uint, interface{}) bool { return false }); c != 0 {
+		t.Logf("Expected 0 matches, saw %d", c)
+		t.Fail()
+	}
+	if c := gerbst.NewLockingTree().CountFunc(even); c != 0 {
+		t.Logf("Expected 0 matches in empty tree, saw %d", c)
+		t.Fail()
+	}
+}
+
+func TestLockingTree_EachKey(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	var all []uint
+	lt.EachKey(func(key uint) bool {
+		all = append(all, key)
+		return true
+	})
+	if s := fmt.Sprint(all); s != "[7 9 11 12 82 90]" {
+		t.Logf("Expected keys in ascending order, saw %s", s)
+		t.Fail()
+	}
+
+	var some []uint
+	lt.EachKey(func(key uint) bool {
+		some = append(some, key)
+		return key < 11
+	})
+	if s := fmt.Sprint(some); s != "[7 9 11]" {
+		t.Logf("Expected walk to halt after key 11, saw %s", s)
+		t.Fail()
+	}
+
+	gerbst.NewLockingTree().EachKey(func(key uint) bool {
+		t.Logf("Expected no keys from an empty tree, saw %d", key)
+		t.Fail()
+		return true
+	})
+}
+
+func TestLockingTree_Where(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+
+	odd := lt.Where(func(key uint, _ interface{}) bool { return key%2 == 1 })
+	keys := make([]uint, len(odd))
+	for i, n := range odd {
+		keys[i] = n.Key()
+	}
+	if s := fmt.Sprint(keys); s != "[7 9 11]" {
+		t.Logf("Expected odd keys in ascending order, saw %s", s)
+		t.Fail()
+	}
+
+	if none := lt.Where(func(uint, interface{}) bool { return false }); len(none) != 0 {
+		t.Logf("Expected no nodes to match, saw %d", len(none))
+		t.Fail()
+	}
+	if c, w := lt.CountFunc(func(key uint, _ interface{}) bool { return key > 10 }),
+		lt.Where(func(key uint, _ interface{}) bool { return key > 10 }); c != uint(len(w)) {
+		t.Logf("Expected Where to agree with CountFunc on %d matches, saw %d", c, len(w))
+		t.Fail()
+	}
+}
+
+func TestLockingTree_InOrderIndexed(t *testing.T) {
+	lt := gerbst.NewLockingTreeWithKeys([]uint{12, 11, 90, 82, 7, 9})
+	expected := []uint{7, 9, 11, 12, 82, 90}
+
+	var visited uint
+	lt.InOrderIndexed(func(index uint, node *gerbst.Node) bool {
+		if index != visited {
+			t.Logf("Expected index %d, saw %d", visited, index)
+			t.Fail()
+		}
+		if node.Key() != expected[index] {
+			t.Logf("Expected key %d at index %d, saw %d", expected[index], index, node.Key())
+			t.Fail()
+		}
+		if rank, _ := lt.Rank(node.Key()); rank != index {
+			t.Logf("Expected index %d to equal rank of key %d, saw %d", index, node.Key(), rank)
+			t.Fail()
+		}
+		visited++
+		return true
+	})
+	if visited != lt.Count() {
+		t.Logf("Expected %d nodes to be visited, saw %d", lt.Count(), visited)
+		t.Fail()
+	}
+
+	var last uint
+	visited = 0
+	lt.InOrderIndexed(func(index uint, node *gerbst.Node) bool {
+		last = index
+		visited++
+		return node.Key() < 11
+	})
+	if visited != 3 || last != 2 {
+		t.Logf("Expected early stop after 3 nodes at index 2, saw %d nodes ending at index %d", visited, last)
+		t.Fail()
+	}
+
+	gerbst.NewLockingTree().InOrderIndexed(func(uint, *gerbst.Node) bool {
+		t.Log("Expected no calls for empty tree")
+		t.Fail()
+		return true
+	})
+}
+
+func TestLockingTree_DFS(t *testing.T) {
+	keys := []uint{12, 11, 90, 82, 7, 9}
+	lt := gerbst.NewLockingTreeWithKeys(keys)
+
+	collect := func(lt *gerbst.LockingTree, rightFirst bool) []uint {
+		var visited []uint
+		lt.DFS(func(n *gerbst.Node) bool {
+			visited = append(visited, n.Key())
+			return true
+		}, rightFirst)
+		return visited
+	}
+
+	if s := fmt.Sprint(collect(lt, false)); s != "[12 11 7 9 90 82]" {
+		t.Logf("Expected left-first order [12 11 7 9 90 82], saw %s", s)
+		t.Fail()
+	}
+	if s := fmt.Sprint(collect(lt, true)); s != "[12 90 82 11 7 9]" {
+		t.Logf("Expected right-first order [12 90 82 11 7 9], saw %s", s)
+		t.Fail()
+	}
+
+	// reflecting every key builds the mirror image of the tree, whose left-first walk is this tree's right-first walk
+	mirrored := make([]uint, len(keys))
+	for i, k := range keys {
+		mirrored[i] = 100 - k
+	}
+	mlt := gerbst.NewLockingTreeWithKeys(mirrored)
+	for _, rightFirst := range []bool{false, true} {
+		expected, actual := collect(lt, rightFirst), collect(mlt, !rightFirst)
+		for i := range actual {
+			actual[i] = 100 - actual[i]
+		}
+		if fmt.Sprint(expected) != fmt.Sprint(actual) {
+			t.Logf("Expected mirrored tree to visit %v with rightFirst %t, saw %v", expected, !rightFirst, actual)
+			t.Fail()
+		}
+	}
+
+	var visited int
+	lt.DFS(func(n *gerbst.Node) bool {
+		visited++
+		return n.Key() != 90
+	}, true)
+	if visited != 2 {
+		t.Logf("Expected DFS to halt after 2 nodes, saw %d", visited)
+		t.Fail()
+	}
+
+	gerbst.NewLockingTree().DFS(func(*gerbst.Node) bool {
+		t.Log("Expected no calls for empty tree")
+		t.Fail()
+		return true
+	}, false)
+}
@@ -0,0 +1,30 @@
+package gerbst
+
+// treapPriority returns the priority of key within a tree built WithRandomizedInsertPriority.  Rather than storing a
+// random priority in each node, the priority is derived by hashing the key with the tree's seed, which gives the same
+// expected balance without growing every node.
+func treapPriority(seed uint64, key uint) uint64 {
+	// splitmix64 finalizer
+	z := uint64(key) + seed + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// treapSiftUp rotates tn above each ancestor holding a lower priority.  Caller must hold the write lock.
+func (n *LockingTree) treapSiftUp(tn *treeNode) {
+	prio := treapPriority(n.treapSeed, tn.key)
+	for tn.parent != nil && prio > treapPriority(n.treapSeed, tn.parent.key) {
+		rotate(&n.root, tn.parent, tn.parent.right == tn)
+	}
+}
+
+// treapSiftDown rotates tn beneath whichever of its children holds the higher priority until it has at most one
+// child, so that it may be removed without disturbing the priority order of the remaining nodes.  Caller must hold
+// the write lock.
+func (n *LockingTree) treapSiftDown(tn *treeNode) {
+	for tn.left != nil && tn.right != nil {
+		left := treapPriority(n.treapSeed, tn.right.key) > treapPriority(n.treapSeed, tn.left.key)
+		rotate(&n.root, tn, left)
+	}
+}
@@ -0,0 +1,71 @@
+package gerbst
+
+import (
+	"fmt"
+)
+
+// TypedTree wraps a LockingTree whose values are all of type V, performing the type assertion on retrieval so callers
+// need not.
+//
+// Values may only be stored through Put, which accepts only V, so the assertion cannot fail unless the underlying tree
+// returned by Inner is written to directly.  Should that happen, retrieving a value of any other type panics.
+type TypedTree[V any] struct {
+	inner *LockingTree
+}
+
+// NewTypedTree constructs a new, empty typed tree configured with the provided options
+func NewTypedTree[V any](opts ...LockingTreeOption) *TypedTree[V] {
+	tt := new(TypedTree[V])
+	tt.inner = NewLockingTree(opts...)
+	return tt
+}
+
+// Inner returns the underlying tree, for access to operations TypedTree does not wrap.  Values stored through it must
+// be of type V.
+func (tt *TypedTree[V]) Inner() *LockingTree {
+	return tt.inner
+}
+
+// Count returns the total number of nodes within this tree
+func (tt *TypedTree[V]) Count() uint {
+	return tt.inner.Count()
+}
+
+// Contains returns true if a node with the provided key exists within this tree
+func (tt *TypedTree[V]) Contains(key uint) bool {
+	return tt.inner.Contains(key)
+}
+
+// Get returns the value stored under key, or the zero value of V and false if the key is absent
+func (tt *TypedTree[V]) Get(key uint) (V, bool) {
+	n, ok := tt.inner.Get(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return tt.assert(n), true
+}
+
+// Put inserts a new node or updates the value of an existing node
+func (tt *TypedTree[V]) Put(key uint, value V) {
+	tt.inner.Put(key, value)
+}
+
+// Delete removes the node with the provided key, returning its value or false if the key was absent
+func (tt *TypedTree[V]) Delete(key uint) (V, bool) {
+	n, ok := tt.inner.Delete(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return tt.assert(n), true
+}
+
+func (tt *TypedTree[V]) assert(n *Node) V {
+	v, ok := n.value.(V)
+	if !ok && n.value != nil {
+		var zero V
+		panic(fmt.Sprintf("gerbst: value for key %d is %T, expected %T", n.key, n.value, zero))
+	}
+	return v
+}
@@ -0,0 +1,45 @@
+package gerbst_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dcarbone/gerbst"
+)
+
+func TestTypedTree(t *testing.T) {
+	tt := gerbst.NewTypedTree[string]()
+	for _, k := range []uint{12, 11, 90, 82, 7, 9} {
+		tt.Put(k, fmt.Sprintf("v%d", k))
+	}
+
+	if c := tt.Count(); c != 6 {
+		t.Logf("Expected count 6, saw %d", c)
+		t.Fail()
+	}
+
+	s, ok := tt.Get(82)
+	if !ok || s != "v82" {
+		t.Logf("Expected Get(82) to return \"v82\", saw %q (%t)", s, ok)
+		t.Fail()
+	}
+	if s, ok := tt.Get(83); ok || s != "" {
+		t.Logf("Expected Get(83) to return zero value and false, saw %q (%t)", s, ok)
+		t.Fail()
+	}
+	if s, ok := tt.Delete(7); !ok || s != "v7" || tt.Contains(7) {
+		t.Logf("Expected Delete(7) to return \"v7\", saw %q (%t)", s, ok)
+		t.Fail()
+	}
+
+	t.Run("mismatch_panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Log("Expected Get of a mismatched value to panic")
+				t.Fail()
+			}
+		}()
+		tt.Inner().Put(1, 1)
+		tt.Get(1)
+	})
+}
@@ -0,0 +1,79 @@
+package gerbst
+
+import (
+	"sort"
+)
+
+// valueIndex maps each value held by a tree to the keys holding it, in ascending order.  Values must be comparable.
+type valueIndex map[interface{}][]uint
+
+// add records that key holds value
+func (vi valueIndex) add(value interface{}, key uint) {
+	keys := vi[value]
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] >= key })
+	if i < len(keys) && keys[i] == key {
+		return
+	}
+	keys = append(keys, 0)
+	copy(keys[i+1:], keys[i:])
+	keys[i] = key
+	vi[value] = keys
+}
+
+// remove records that key no longer holds value
+func (vi valueIndex) remove(value interface{}, key uint) {
+	keys := vi[value]
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] >= key })
+	if i == len(keys) || keys[i] != key {
+		return
+	}
+	if len(keys) == 1 {
+		delete(vi, value)
+		return
+	}
+	vi[value] = append(keys[:i:i], keys[i+1:]...)
+}
+
+// reindex rebuilds the index from the subtree beneath root, which may be nil
+func (vi valueIndex) reindex(root *treeNode) {
+	for v := range vi {
+		delete(vi, v)
+	}
+	if root == nil {
+		return
+	}
+	root.walkInOrder(func(tn *treeNode) bool {
+		vi[tn.value] = append(vi[tn.value], tn.key)
+		return true
+	})
+}
+
+// KeysForValue returns the keys of every node holding a value equal to value, in ascending order.  With WithValueIndex
+// this is a single map lookup, otherwise every node is compared.
+func (n *LockingTree) KeysForValue(value interface{}) []uint {
+	if n == nil {
+		return nil
+	}
+	n.rlock()
+	defer n.mu.RUnlock()
+	if n.root == nil {
+		return nil
+	}
+	if n.valueIndex != nil {
+		keys := n.valueIndex[value]
+		if len(keys) == 0 {
+			return nil
+		}
+		out := make([]uint, len(keys))
+		copy(out, keys)
+		return out
+	}
+	var keys []uint
+	n.root.walkInOrder(func(tn *treeNode) bool {
+		if tn.value == value {
+			keys = append(keys, tn.key)
+		}
+		return true
+	})
+	return keys
+}